@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trustEndpoint 为 true 时跳过 allowed_endpoints 检查 (--trust-endpoint)
+var trustEndpoint bool
+
+// checkEndpointAllowed 检查端点是否在 allowed_endpoints 中
+//
+// 列表为空时不做限制。列表项可以是主机名 (api.openai.com)、带端口的主机
+// (localhost:11434) 或 URL 前缀 (https://gateway.example.com/v1/)。
+func checkEndpointAllowed(endpoint string) error {
+	if trustEndpoint || len(config.AllowedEndpoints) == 0 {
+		return nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return newError("allowlist.denied", endpoint, configFilePathForDisplay())
+	}
+
+	for _, allowed := range config.AllowedEndpoints {
+		if endpointMatches(u, endpoint, allowed) {
+			return nil
+		}
+	}
+
+	return newError("allowlist.denied", endpoint, configFilePathForDisplay())
+}
+
+// endpointMatches 判断端点是否匹配一个允许项
+func endpointMatches(u *url.URL, endpoint, allowed string) bool {
+	allowed = strings.TrimSpace(allowed)
+	if allowed == "" {
+		return false
+	}
+
+	if strings.Contains(allowed, "://") {
+		a, err := url.Parse(allowed)
+		if err != nil || !strings.EqualFold(a.Scheme, u.Scheme) || !strings.EqualFold(a.Host, u.Host) {
+			return false
+		}
+		// 路径前缀需要在 / 处对齐，避免 /v1 匹配 /v1-evil
+		prefix := strings.TrimSuffix(a.Path, "/")
+		return u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/")
+	}
+
+	if strings.Contains(allowed, ":") {
+		return strings.EqualFold(u.Host, allowed)
+	}
+	return strings.EqualFold(u.Hostname(), allowed)
+}
+
+// configFilePathForDisplay 返回用于提示信息的配置文件路径
+func configFilePathForDisplay() string {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return "~/" + configDirName + "/" + configFileName
+	}
+	return configPath
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestEndpointMatches(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		allowed  string
+		want     bool
+	}{
+		// 主机名
+		{"https://api.openai.com/v1/chat/completions", "api.openai.com", true},
+		{"https://API.OpenAI.com/v1/chat/completions", "api.openai.com", true},
+		{"https://api.openai.com:8443/v1", "api.openai.com", true},
+		{"https://api.openai.com.evil.com/v1", "api.openai.com", false},
+		{"https://evil.com/api.openai.com", "api.openai.com", false},
+		// 带端口的主机
+		{"http://localhost:11434/v1/chat/completions", "localhost:11434", true},
+		{"http://localhost:11435/v1/chat/completions", "localhost:11434", false},
+		{"http://localhost/v1/chat/completions", "localhost:11434", false},
+		// URL 前缀
+		{"https://gateway.example.com/v1/chat/completions", "https://gateway.example.com/v1/", true},
+		{"https://gateway.example.com/v1", "https://gateway.example.com/v1", true},
+		{"https://gateway.example.com/v1-evil/chat", "https://gateway.example.com/v1", false},
+		{"http://gateway.example.com/v1/chat/completions", "https://gateway.example.com/v1/", false},
+		{"https://gateway.example.com:444/v1/chat", "https://gateway.example.com/v1/", false},
+		{"https://gateway.example.com/anything", "https://gateway.example.com", true},
+		// 空项不匹配任何端点
+		{"https://api.openai.com/v1", "", false},
+		{"https://api.openai.com/v1", "  ", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.endpoint)
+		if err != nil {
+			t.Fatalf("url.Parse(%q): %v", tt.endpoint, err)
+		}
+		if got := endpointMatches(u, tt.endpoint, tt.allowed); got != tt.want {
+			t.Errorf("endpointMatches(%q, %q) = %v, want %v", tt.endpoint, tt.allowed, got, tt.want)
+		}
+	}
+}
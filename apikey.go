@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+const genericAPIKeyEnv = "AICOMMIT_API_KEY"
+
+// providerKeyEnvs 端点主机名到对应服务商 API 密钥环境变量的映射
+var providerKeyEnvs = []struct {
+	hostSuffix string
+	env        string
+}{
+	{"openai.com", "OPENAI_API_KEY"},
+	{"anthropic.com", "ANTHROPIC_API_KEY"},
+	{"openrouter.ai", "OPENROUTER_API_KEY"},
+	{"deepseek.com", "DEEPSEEK_API_KEY"},
+	{"groq.com", "GROQ_API_KEY"},
+	{"mistral.ai", "MISTRAL_API_KEY"},
+	{"googleapis.com", "GEMINI_API_KEY"},
+	{"x.ai", "XAI_API_KEY"},
+	{"dashscope.aliyuncs.com", "DASHSCOPE_API_KEY"},
+	{"moonshot.cn", "MOONSHOT_API_KEY"},
+}
+
+// apiKeyEnvCandidates 返回按优先级排列的 API 密钥环境变量
+//
+// 只使用与端点匹配的服务商密钥，避免把一家服务商的密钥发送给另一家。
+// 无法识别服务商的端点 (自建网关等) 按 OpenAI 兼容接口处理。
+func apiKeyEnvCandidates(endpoint string) []string {
+	candidates := []string{genericAPIKeyEnv}
+
+	host := ""
+	if u, err := url.Parse(endpoint); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+	for _, p := range providerKeyEnvs {
+		if host == p.hostSuffix || strings.HasSuffix(host, "."+p.hostSuffix) {
+			return append(candidates, p.env)
+		}
+	}
+
+	return append(candidates, "OPENAI_API_KEY")
+}
+
+// apiKeyFromEnv 从环境变量中读取 API 密钥，返回密钥和环境变量名
+func apiKeyFromEnv(endpoint string) (string, string) {
+	for _, name := range apiKeyEnvCandidates(endpoint) {
+		if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+			return value, name
+		}
+	}
+	return "", ""
+}
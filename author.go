@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/mail"
+	"os"
+)
+
+// parseCommitAs 解析 commit_as 中 "Name <email>" 形式的身份
+func parseCommitAs(value string) (*mail.Address, error) {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Name == "" {
+		return nil, newError("commit_as.invalid", value)
+	}
+	return addr, nil
+}
+
+// applyCommitAs 配置了 commit_as 时将其设为本次运行提交的作者
+//
+// 除了提交时传入 --author，还设置 GIT_AUTHOR_NAME 和 GIT_AUTHOR_EMAIL，
+// 使钩子和 git var GIT_AUTHOR_IDENT 看到的作者与最终提交一致。
+func applyCommitAs() error {
+	if config.CommitAs == "" {
+		return nil
+	}
+	addr, err := parseCommitAs(config.CommitAs)
+	if err != nil {
+		return err
+	}
+	os.Setenv("GIT_AUTHOR_NAME", addr.Name)
+	os.Setenv("GIT_AUTHOR_EMAIL", addr.Address)
+	return nil
+}
+
+// withAuthor 配置了 commit_as 时在 git commit 参数中加入 --author
+func withAuthor(args ...string) []string {
+	if config.CommitAs == "" {
+		return args
+	}
+	return append(args, "--author="+config.CommitAs)
+}
+
+// validateCommitAs 校验 commit_as，用户配置和服务商配置共用
+func validateCommitAs(key, value string, lines map[string]int) []configIssue {
+	if value == "" {
+		return nil
+	}
+	if _, err := parseCommitAs(value); err != nil {
+		return []configIssue{{line: lines[key], key: key, message: err.Error()}}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const defaultBenchRuns = 5
+
+// benchDiff 基准测试使用的固定差异，大小与日常的小型提交相当，结果在不同机器之间可以比较
+const benchDiff = `diff --git a/internal/cache/cache.go b/internal/cache/cache.go
+index 3b18e51..a9c4f27 100644
+--- a/internal/cache/cache.go
++++ b/internal/cache/cache.go
+@@ -1,30 +1,52 @@
+ package cache
+
+ import (
+ 	"sync"
++	"time"
+ )
+
+-// Cache is a concurrency-safe in-memory key/value store.
++// Cache is a concurrency-safe in-memory key/value store with per-entry expiry.
+ type Cache struct {
+ 	mu    sync.RWMutex
+-	items map[string]string
++	items map[string]entry
++	ttl   time.Duration
+ }
+
+-func New() *Cache {
+-	return &Cache{items: make(map[string]string)}
++type entry struct {
++	value   string
++	expires time.Time
++}
++
++// New returns a cache whose entries expire after ttl; a zero ttl disables expiry.
++func New(ttl time.Duration) *Cache {
++	return &Cache{items: make(map[string]entry), ttl: ttl}
+ }
+
+ func (c *Cache) Get(key string) (string, bool) {
+ 	c.mu.RLock()
+ 	defer c.mu.RUnlock()
+-	v, ok := c.items[key]
+-	return v, ok
++	e, ok := c.items[key]
++	if !ok || c.expired(e) {
++		return "", false
++	}
++	return e.value, true
+ }
+
+ func (c *Cache) Set(key, value string) {
+ 	c.mu.Lock()
+ 	defer c.mu.Unlock()
+-	c.items[key] = value
++	e := entry{value: value}
++	if c.ttl > 0 {
++		e.expires = time.Now().Add(c.ttl)
++	}
++	c.items[key] = e
++}
++
++func (c *Cache) expired(e entry) bool {
++	return !e.expires.IsZero() && time.Now().After(e.expires)
+ }
+diff --git a/internal/server/server.go b/internal/server/server.go
+index 7d2e0c4..1f5b8a3 100644
+--- a/internal/server/server.go
++++ b/internal/server/server.go
+@@ -18,7 +18,7 @@ func NewServer(cfg Config) *Server {
+ 	return &Server{
+ 		cfg:    cfg,
+-		cache:  cache.New(),
++		cache:  cache.New(cfg.CacheTTL),
+ 		logger: cfg.Logger,
+ 	}
+ }
+diff --git a/internal/server/config.go b/internal/server/config.go
+index 5c0a1e2..8e4d9b7 100644
+--- a/internal/server/config.go
++++ b/internal/server/config.go
+@@ -9,4 +9,6 @@ type Config struct {
+ 	Addr    string
+ 	Logger  *log.Logger
++	// CacheTTL is how long cached responses stay valid; zero keeps them forever.
++	CacheTTL time.Duration
+ }
+`
+
+const benchStat = ` internal/cache/cache.go   | 38 ++++++++++++++++++++++++++++++--------
+ internal/server/config.go |  2 ++
+ internal/server/server.go |  2 +-
+ 3 files changed, 33 insertions(+), 9 deletions(-)`
+
+// benchRun 一次请求的结果
+type benchRun struct {
+	latency time.Duration
+	tokens  int
+	err     error
+}
+
+// tokensPerSecond 每秒生成的令牌数，按端到端耗时计算，服务商未返回用量时为 0
+func (r benchRun) tokensPerSecond() float64 {
+	if r.tokens == 0 || r.latency <= 0 {
+		return 0
+	}
+	return float64(r.tokens) / r.latency.Seconds()
+}
+
+func runBench(args []string) {
+	runs := defaultBenchRuns
+	var models []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if value, ok := flagValue(args, &i, "--runs"); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				fail("arg.invalid_runs", value)
+			}
+			runs = n
+		} else if value, ok := flagValue(args, &i, "--models"); ok {
+			models = append(models, splitList(value)...)
+		} else {
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	if err := loadConfig(); err != nil {
+		fail("err.load_config", err)
+	}
+	if len(models) == 0 {
+		models = []string{config.Model}
+	}
+
+	// 不读取当前仓库，只使用固定的差异和内置的提交信息模板
+	files := splitDiff(benchDiff)
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	prompt := chatPrompt{text: renderTemplate("commit", promptData{
+		Diff:  benchDiff,
+		Stat:  benchStat,
+		Lang:  config.DefaultLang,
+		Files: paths,
+	})}
+
+	fmt.Fprintln(stdout, tr("bench.start", config.OpenAIEndpoint, runs, len(prompt.text)))
+	results := make([][]benchRun, len(models))
+	for i, model := range models {
+		fmt.Fprintln(stdout)
+		fmt.Fprintln(stdout, tr("bench.model", model))
+		settings := chatSettings{model: model, temperature: config.Temperature}
+
+		// 第一次请求包括建立连接、探测服务商能力和本地模型的加载，单独显示，不计入统计
+		warmup := benchRequest(prompt, settings)
+		if warmup.err != nil {
+			fmt.Fprintln(stdout, tr("bench.failed", tr("bench.warmup"), warmup.err))
+		} else {
+			fmt.Fprintln(stdout, tr("bench.run", tr("bench.warmup"), formatLatency(warmup.latency), formatTokens(warmup)))
+		}
+
+		for run := 1; run <= runs; run++ {
+			r := benchRequest(prompt, settings)
+			label := fmt.Sprint(run)
+			if r.err != nil {
+				fmt.Fprintln(stdout, tr("bench.failed", label, r.err))
+			} else {
+				fmt.Fprintln(stdout, tr("bench.run", label, formatLatency(r.latency), formatTokens(r)))
+			}
+			results[i] = append(results[i], r)
+		}
+	}
+
+	fmt.Fprintln(stdout)
+	for i, model := range models {
+		fmt.Fprintln(stdout, benchSummary(model, results[i]))
+	}
+}
+
+// benchRequest 发送一次请求并计时
+func benchRequest(prompt chatPrompt, settings chatSettings) benchRun {
+	start := time.Now()
+	result, err := requestPromptWith(prompt, settings)
+	return benchRun{latency: time.Since(start), tokens: result.usage.CompletionTokens, err: err}
+}
+
+// benchSummary 汇总一个模型的延迟分位数和生成速度
+func benchSummary(model string, runs []benchRun) string {
+	var latencies []time.Duration
+	var speeds []float64
+	failed := 0
+	for _, r := range runs {
+		if r.err != nil {
+			failed++
+			continue
+		}
+		latencies = append(latencies, r.latency)
+		if speed := r.tokensPerSecond(); speed > 0 {
+			speeds = append(speeds, speed)
+		}
+	}
+	if len(latencies) == 0 {
+		return tr("bench.summary_failed", model, failed, len(runs))
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	sort.Float64s(speeds)
+	speed := "-"
+	if len(speeds) > 0 {
+		speed = fmt.Sprintf("%.1f", speeds[(len(speeds)-1)/2])
+	}
+	return tr("bench.summary", model,
+		formatLatency(percentile(latencies, 50)), formatLatency(percentile(latencies, 95)),
+		formatLatency(latencies[0]), formatLatency(latencies[len(latencies)-1]),
+		speed, failed, len(runs))
+}
+
+// percentile 按最近秩法返回已排序耗时的第 p 百分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// formatLatency 显示耗时，不到一秒时以毫秒为单位
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
+
+// formatTokens 显示生成的令牌数和速度，服务商未返回用量时显示 -
+func formatTokens(r benchRun) string {
+	if r.tokens == 0 {
+		return tr("bench.no_usage")
+	}
+	return tr("bench.tokens", r.tokens, r.tokensPerSecond())
+}
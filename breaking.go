@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const breakingChangeFooter = "BREAKING CHANGE"
+
+// breakingHeaderPattern 拆分 Conventional Commits 标题的 "<type>(<scope>)"、"!" 和描述
+var breakingHeaderPattern = regexp.MustCompile(`^([a-z]+(?:\([^)]+\))?)(!?): (.*)$`)
+
+// breakingFooterPattern 匹配 BREAKING CHANGE 或 BREAKING-CHANGE 脚注，大小写写错的也算
+var breakingFooterPattern = regexp.MustCompile(`(?i)^breaking[ -]change: *(.*)$`)
+
+// trailerLinePattern 匹配 "Key: value" 形式的 trailer 行
+var trailerLinePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*: \S`)
+
+// breakingHint 使用 --breaking 时要求模型标记破坏性变更
+func breakingHint(breaking bool, note string) string {
+	if !breaking {
+		return ""
+	}
+	hint := "This change is a breaking change."
+	if note != "" {
+		hint += " What breaks: " + note + "."
+	}
+	return hint + " If the commit message uses Conventional Commits, put ! right after the type/scope (for example \"feat(api)!: ...\") and end the message with a \"BREAKING CHANGE: <what breaks and how to migrate>\" footer."
+}
+
+// splitBreakingMarkers 检查提交信息中的 "!" 标记和 BREAKING CHANGE 脚注
+func splitBreakingMarkers(message string) (conventional, bang, footer bool) {
+	subject := messageSubject(message)
+	_, text := splitLeadingEmoji(subject)
+	if match := breakingHeaderPattern.FindStringSubmatch(text); match != nil {
+		conventional = true
+		bang = match[2] == "!"
+	}
+	for _, line := range strings.Split(message, "\n")[1:] {
+		if breakingFooterPattern.MatchString(strings.TrimSpace(line)) {
+			footer = true
+		}
+	}
+	return conventional, bang, footer
+}
+
+// applyBreakingChange 使破坏性变更的两种标记保持一致，补上模型遗漏的一个
+//
+// 标题带有 "!"、正文带有 BREAKING CHANGE 脚注，或使用了 --breaking 时，视为破坏性变更：
+// Conventional Commits 标题缺少 "!" 时补上，缺少脚注时根据 --breaking 的说明或标题生成，
+// 大小写不规范的脚注改为 "BREAKING CHANGE:"。
+func applyBreakingChange(message string, breaking bool, note string) string {
+	conventional, bang, footer := splitBreakingMarkers(message)
+	if !breaking && !bang && !footer {
+		return message
+	}
+
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+	emoji, text := splitLeadingEmoji(lines[0])
+	match := breakingHeaderPattern.FindStringSubmatch(text)
+	if conventional && !bang {
+		text = match[1] + "!: " + match[3]
+		lines[0] = text
+		if emoji != "" {
+			lines[0] = emoji + " " + text
+		}
+		fmt.Fprintln(stdout, tr("breaking.marker_added"))
+	}
+
+	for i, line := range lines[1:] {
+		if m := breakingFooterPattern.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			lines[i+1] = breakingChangeFooter + ": " + m[1]
+		}
+	}
+	message = strings.Join(lines, "\n")
+	if footer {
+		return message
+	}
+
+	description := note
+	if description == "" && match != nil {
+		description = match[3]
+	}
+	if description == "" {
+		description = text
+	}
+	fmt.Fprintln(stdout, tr("breaking.footer_added"))
+	return appendFooter(message, breakingChangeFooter, description)
+}
+
+// appendFooter 追加一个脚注，信息末尾已经是 trailer 段落时放在该段落中，不另起段落
+func appendFooter(message, key, value string) string {
+	message = strings.TrimRight(message, "\n")
+	paragraphs := strings.Split(message, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if len(paragraphs) > 1 && isTrailerParagraph(last) {
+		return message + "\n" + key + ": " + value
+	}
+	return appendTrailer(message, key, value)
+}
+
+// isTrailerParagraph 判断段落是否全部由 trailer 行组成
+func isTrailerParagraph(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerLinePattern.MatchString(line) && !breakingFooterPattern.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func TestApplyBreakingChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		breaking bool
+		note     string
+		want     string
+	}{
+		{
+			"not breaking",
+			"feat(api): add filter",
+			false, "",
+			"feat(api): add filter",
+		},
+		{
+			"bang adds footer from subject",
+			"feat(api)!: drop v1 endpoints",
+			false, "",
+			"feat(api)!: drop v1 endpoints\n\nBREAKING CHANGE: drop v1 endpoints",
+		},
+		{
+			"footer adds bang",
+			"feat(api): drop v1 endpoints\n\nBREAKING CHANGE: v1 is gone",
+			false, "",
+			"feat(api)!: drop v1 endpoints\n\nBREAKING CHANGE: v1 is gone",
+		},
+		{
+			"footer spelling normalized",
+			"fix!: rename flag\n\nbreaking-change:  use --out instead",
+			false, "",
+			"fix!: rename flag\n\nBREAKING CHANGE: use --out instead",
+		},
+		{
+			"flag with note",
+			"refactor: rename config keys",
+			true, "api_key is now openai_api_key",
+			"refactor!: rename config keys\n\nBREAKING CHANGE: api_key is now openai_api_key",
+		},
+		{
+			"footer joins trailer paragraph",
+			"feat!: new storage\n\nBody.\n\nRefs: #12",
+			false, "",
+			"feat!: new storage\n\nBody.\n\nRefs: #12\nBREAKING CHANGE: new storage",
+		},
+		{
+			"emoji kept before type",
+			"✨ feat: remove legacy mode\n\nBREAKING CHANGE: legacy mode removed",
+			false, "",
+			"✨ feat!: remove legacy mode\n\nBREAKING CHANGE: legacy mode removed",
+		},
+		{
+			"non-conventional subject only gets footer",
+			"Remove legacy mode",
+			true, "",
+			"Remove legacy mode\n\nBREAKING CHANGE: Remove legacy mode",
+		},
+	}
+	for _, tt := range tests {
+		if got := applyBreakingChange(tt.message, tt.breaking, tt.note); got != tt.want {
+			t.Errorf("%s: applyBreakingChange(%q) = %q, want %q", tt.name, tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestAppendFooter(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"feat: a", "feat: a\n\nKey: v"},
+		{"feat: a\n\nBody text.\n", "feat: a\n\nBody text.\n\nKey: v"},
+		{"feat: a\n\nSigned-off-by: A <a@example.com>", "feat: a\n\nSigned-off-by: A <a@example.com>\nKey: v"},
+		{"feat: a\n\nBody.\nRefs: #1", "feat: a\n\nBody.\nRefs: #1\n\nKey: v"},
+	}
+	for _, tt := range tests {
+		if got := appendFooter(tt.message, "Key", "v"); got != tt.want {
+			t.Errorf("appendFooter(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// capabilitiesFileName 探测到的服务商能力，保存在配置目录中
+	capabilitiesFileName = "capabilities.json"
+
+	// capabilitiesVersion 探测结果的格式版本，增加探测项目后加一，旧版本的结果会重新探测
+	capabilitiesVersion = 1
+
+	maxTokensField           = "max_tokens"
+	maxCompletionTokensField = "max_completion_tokens"
+
+	// probeMaxTokens 探测请求生成的最大令牌数，只需要模型回答一个词
+	probeMaxTokens = 16
+	// maxProbeAttempts 根据错误信息调整请求的最多次数
+	maxProbeAttempts = 4
+	// probeFailureTTL 探测失败后在这段时间内不再探测，避免无法连接的端点每次运行都重新探测
+	probeFailureTTL = 10 * time.Minute
+)
+
+// Capabilities 服务商支持的请求格式，未设置的项按当前 OpenAI 接口处理
+type Capabilities struct {
+	Stream         *bool  `json:"stream,omitempty"`
+	SystemRole     *bool  `json:"system_role,omitempty"`
+	Temperature    *bool  `json:"temperature,omitempty"`
+	MaxTokensField string `json:"max_tokens_field,omitempty"`
+}
+
+// probedCapabilities 一个端点和模型的探测结果，Failed 为 true 时表示探测失败
+type probedCapabilities struct {
+	Version  int          `json:"version"`
+	ProbedAt int64        `json:"probed_at"`
+	Failed   bool         `json:"failed,omitempty"`
+	Result   Capabilities `json:"capabilities"`
+}
+
+// usable 判断保存的结果是否可以直接使用，探测失败的结果在 probeFailureTTL 内有效
+func (p probedCapabilities) usable() bool {
+	if p.Version != capabilitiesVersion {
+		return false
+	}
+	return !p.Failed || time.Since(time.Unix(p.ProbedAt, 0)) < probeFailureTTL
+}
+
+var (
+	capabilitiesMu    sync.Mutex
+	capabilitiesCache map[string]*Capabilities
+)
+
+// supports 返回能力是否可用，未知时视为可用
+func supports(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+// tokensField 返回请求中最大令牌数使用的字段名
+func (c *Capabilities) tokensField() string {
+	if c.MaxTokensField == "" {
+		return maxTokensField
+	}
+	return c.MaxTokensField
+}
+
+// describe 返回能力的简短说明，用于 doctor
+func (c *Capabilities) describe() string {
+	mark := func(flag *bool) string {
+		if flag == nil {
+			return "?"
+		}
+		if *flag {
+			return "yes"
+		}
+		return "no"
+	}
+	return fmt.Sprintf("stream=%s, system_role=%s, temperature=%s, max_tokens_field=%s",
+		mark(c.Stream), mark(c.SystemRole), mark(c.Temperature), c.tokensField())
+}
+
+// capabilitiesKey 探测结果按端点和模型保存，openai_endpoints 中的端点视为同一服务商
+func capabilitiesKey(model string) string {
+	return config.OpenAIEndpoint + " " + model
+}
+
+// capabilitiesPath 返回探测结果文件的路径
+func capabilitiesPath() (string, error) {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), capabilitiesFileName), nil
+}
+
+// loadProbedCapabilities 读取所有探测结果，文件损坏或不存在时返回空
+func loadProbedCapabilities() map[string]probedCapabilities {
+	probed := map[string]probedCapabilities{}
+	if path, err := capabilitiesPath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &probed)
+		}
+	}
+	return probed
+}
+
+// saveProbedCapabilities 保存一个端点和模型的探测结果，先写入临时文件再改名
+func saveProbedCapabilities(key string, c Capabilities) {
+	saveProbed(key, probedCapabilities{Version: capabilitiesVersion, ProbedAt: time.Now().Unix(), Result: c})
+}
+
+// saveProbeFailure 记录探测失败，probeFailureTTL 内按默认格式请求，不再探测
+func saveProbeFailure(key string) {
+	saveProbed(key, probedCapabilities{Version: capabilitiesVersion, ProbedAt: time.Now().Unix(), Failed: true})
+}
+
+func saveProbed(key string, p probedCapabilities) {
+	path, err := capabilitiesPath()
+	if err != nil {
+		return
+	}
+	probed := loadProbedCapabilities()
+	probed[key] = p
+	data, err := json.MarshalIndent(probed, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// providerCapabilities 返回请求使用的能力
+//
+// 配置了 capabilities 时直接使用；否则每个端点和模型第一次请求前探测一次并保存，
+// 之后的运行直接读取。探测失败（网络错误、密钥无效等）时按当前 OpenAI 接口处理，
+// 失败也会记录，probeFailureTTL 之后再重新探测。
+func providerCapabilities(model string) *Capabilities {
+	if config.Capabilities != nil {
+		return config.Capabilities
+	}
+
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+
+	key := capabilitiesKey(model)
+	if c, ok := capabilitiesCache[key]; ok {
+		return c
+	}
+	if capabilitiesCache == nil {
+		capabilitiesCache = map[string]*Capabilities{}
+	}
+
+	if p, ok := loadProbedCapabilities()[key]; ok && p.usable() {
+		capabilitiesCache[key] = &p.Result
+		return &p.Result
+	}
+
+	// 设置了 --deadline 时不探测，探测的几个请求会占用截止时间；按默认格式请求，
+	// 结果也不保存，之后不限时的运行再探测
+	if !generationDeadline.IsZero() {
+		c := &Capabilities{}
+		capabilitiesCache[key] = c
+		return c
+	}
+
+	c := &Capabilities{}
+	fmt.Fprintln(stderr, tr("caps.probing", config.OpenAIEndpoint, model))
+	if probed, err := probeCapabilities(model); err == nil {
+		c = &probed
+		saveProbedCapabilities(key, probed)
+	} else {
+		fmt.Fprintln(stderr, tr("caps.probe_failed", err))
+		saveProbeFailure(key)
+	}
+	capabilitiesCache[key] = c
+	return c
+}
+
+// probeResult 一次探测请求的结果
+type probeResult struct {
+	status      int
+	contentType string
+	message     string
+}
+
+// sendProbe 发送一个探测请求
+func sendProbe(client *http.Client, body map[string]interface{}) (probeResult, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return probeResult{}, err
+	}
+	req, err := http.NewRequest("POST", config.OpenAIEndpoint, bytes.NewReader(data))
+	if err != nil {
+		return probeResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+config.APIKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return probeResult{}, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	result := probeResult{status: resp.StatusCode, contentType: resp.Header.Get("Content-Type")}
+	if resp.StatusCode >= 400 {
+		// 错误信息不是 JSON 时直接在响应内容中查找参数名
+		var value interface{}
+		result.message = truncateBody(respBody)
+		if json.Unmarshal(respBody, &value) == nil {
+			if message := responseErrorMessage(value, resp.StatusCode); message != "" {
+				result.message = message
+			}
+		}
+		result.message = strings.ToLower(result.message)
+	}
+	return result, nil
+}
+
+// probeCapabilities 用几个很短的请求探测服务商支持的请求格式
+//
+// 先发送与正常请求相同格式的请求，被拒绝时根据错误信息依次尝试去掉 temperature、
+// 改用 max_completion_tokens、把 system 消息合并到 user 消息中；请求成功后再探测
+// 流式响应。鉴权失败、接口不存在等与格式无关的错误直接返回。
+func probeCapabilities(model string) (Capabilities, error) {
+	if err := checkEndpointAllowed(config.OpenAIEndpoint); err != nil {
+		return Capabilities{}, err
+	}
+	client, err := newHTTPClient(requestTimeout())
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	c := Capabilities{SystemRole: boolPtr(true), Temperature: boolPtr(true), MaxTokensField: maxTokensField}
+	body := func(prompt string) map[string]interface{} {
+		b := map[string]interface{}{"model": model, c.tokensField(): probeMaxTokens}
+		if *c.Temperature {
+			b["temperature"] = config.Temperature
+		}
+		if *c.SystemRole {
+			b["messages"] = []message{{Role: "system", Content: "Answer with one word."}, {Role: "user", Content: prompt}}
+		} else {
+			b["messages"] = []message{{Role: "user", Content: "Answer with one word. " + prompt}}
+		}
+		return b
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := sendProbe(client, body("Say OK."))
+		if err != nil {
+			return Capabilities{}, err
+		}
+		if result.status < 400 {
+			break
+		}
+		if attempt+1 >= maxProbeAttempts || result.status != http.StatusBadRequest && result.status != http.StatusUnprocessableEntity {
+			return Capabilities{}, newError("caps.http_error", result.status, result.message)
+		}
+		switch {
+		case *c.Temperature && strings.Contains(result.message, "temperature"):
+			c.Temperature = boolPtr(false)
+		case c.MaxTokensField == maxTokensField && strings.Contains(result.message, maxTokensField):
+			c.MaxTokensField = maxCompletionTokensField
+		case *c.SystemRole && (strings.Contains(result.message, "system") || strings.Contains(result.message, "role")):
+			c.SystemRole = boolPtr(false)
+		default:
+			return Capabilities{}, newError("caps.http_error", result.status, result.message)
+		}
+	}
+
+	streamBody := body("Say OK.")
+	streamBody["stream"] = true
+	if result, err := sendProbe(client, streamBody); err == nil {
+		c.Stream = boolPtr(result.status < 400 && strings.HasPrefix(result.contentType, "text/event-stream"))
+	}
+
+	return c, nil
+}
+
+// applyCapabilities 按服务商的能力调整请求
+func applyCapabilities(reqBody *openAIRequest, c *Capabilities) {
+	if c.tokensField() == maxCompletionTokensField {
+		reqBody.MaxCompletionTokens, reqBody.MaxTokens = reqBody.MaxTokens, 0
+	}
+	if !supports(c.Temperature) {
+		reqBody.Temperature = nil
+	}
+	if !supports(c.SystemRole) {
+		reqBody.Messages = mergeSystemMessages(reqBody.Messages)
+	}
+}
+
+// mergeSystemMessages 不支持 system 消息时把 system 消息的内容放到第一条 user 消息前面
+func mergeSystemMessages(messages []message) []message {
+	var system []string
+	var merged []message
+	for _, m := range messages {
+		if m.Role != "system" {
+			merged = append(merged, m)
+			continue
+		}
+		switch content := m.Content.(type) {
+		case string:
+			system = append(system, content)
+		case []contentPart:
+			for _, part := range content {
+				system = append(system, part.Text)
+			}
+		}
+	}
+	if len(system) == 0 || len(merged) == 0 {
+		return messages
+	}
+	if text, ok := merged[0].Content.(string); ok {
+		merged[0].Content = strings.Join(system, "\n\n") + "\n\n" + text
+	}
+	return merged
+}
+
+// validateCapabilities 校验 capabilities.max_tokens_field
+func validateCapabilities(c *Capabilities, lines map[string]int) []configIssue {
+	if c == nil {
+		return nil
+	}
+	switch c.MaxTokensField {
+	case "", maxTokensField, maxCompletionTokensField:
+		return nil
+	}
+	key := "capabilities.max_tokens_field"
+	return []configIssue{{line: lines[key], key: key, message: tr("schema.invalid_choice", maxTokensField+", "+maxCompletionTokensField)}}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProbedCapabilitiesUsable(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		probe probedCapabilities
+		want  bool
+	}{
+		{"probed", probedCapabilities{Version: capabilitiesVersion, ProbedAt: now.Add(-30 * 24 * time.Hour).Unix()}, true},
+		{"old format", probedCapabilities{Version: capabilitiesVersion - 1, ProbedAt: now.Unix()}, false},
+		{"recent failure", probedCapabilities{Version: capabilitiesVersion, ProbedAt: now.Add(-time.Minute).Unix(), Failed: true}, true},
+		{"expired failure", probedCapabilities{Version: capabilitiesVersion, ProbedAt: now.Add(-probeFailureTTL - time.Minute).Unix(), Failed: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.probe.usable(); got != tt.want {
+			t.Errorf("%s: usable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
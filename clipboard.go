@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"unicode/utf16"
+)
+
+// clipboardCommand 系统剪贴板命令
+type clipboardCommand struct {
+	name string
+	args []string
+}
+
+// clipboardCommands 返回当前系统可用的剪贴板命令，按优先级排列
+func clipboardCommands() []clipboardCommand {
+	switch runtime.GOOS {
+	case "darwin":
+		return []clipboardCommand{{name: "pbcopy"}}
+	case "windows":
+		return []clipboardCommand{{name: "clip"}}
+	}
+
+	var commands []clipboardCommand
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, clipboardCommand{name: "wl-copy"})
+	}
+	return append(commands,
+		clipboardCommand{name: "xclip", args: []string{"-selection", "clipboard"}},
+		clipboardCommand{name: "xsel", args: []string{"--clipboard", "--input"}},
+		// WSL 中可以直接调用 Windows 的剪贴板
+		clipboardCommand{name: "clip.exe"},
+	)
+}
+
+// clipboardInput 返回写入剪贴板命令的内容
+//
+// clip 按系统代码页解析输入，写入带 BOM 的 UTF-16 才能正确保留中文等字符。
+func clipboardInput(name, text string) []byte {
+	if name != "clip" && name != "clip.exe" {
+		return []byte(text)
+	}
+
+	var b bytes.Buffer
+	b.Write([]byte{0xFF, 0xFE})
+	for _, u := range utf16.Encode([]rune(text)) {
+		b.WriteByte(byte(u))
+		b.WriteByte(byte(u >> 8))
+	}
+	return b.Bytes()
+}
+
+// copyToClipboard 将文本复制到系统剪贴板
+//
+// 没有可用的剪贴板命令时（如通过 SSH 连接的服务器），向终端输出 OSC 52 转义序列，
+// 由支持该序列的终端写入本地剪贴板；纯文本输出模式和不支持转义序列的 Windows 控制台中不输出。
+func copyToClipboard(text string) error {
+	for _, c := range clipboardCommands() {
+		path, err := exec.LookPath(c.name)
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, c.args...)
+		cmd.Stdin = bytes.NewReader(clipboardInput(c.name, text))
+		if err := cmd.Run(); err == nil {
+			return nil
+		}
+	}
+
+	if supportsANSI(os.Stderr) {
+		fmt.Fprintf(stderr, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+		return nil
+	}
+	return newError("clipboard.unavailable")
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	defaultCommentChar = "#"
+
+	// autoCommentChars core.commentChar=auto 时 git 依次尝试的注释字符
+	autoCommentChars = "#;@!$%^&|:"
+
+	editMsgFileName = "AICOMMIT_EDITMSG"
+)
+
+// gitConfigValue 读取 git 配置项，未设置时返回空
+func gitConfigValue(key string) string {
+	output, err := gitOutput("config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
+
+// commentChar 返回 git 用于注释行的字符
+//
+// 与 git 相同，core.commentChar=auto 时选择第一个没有出现在任何行首的字符。
+func commentChar(message string) string {
+	char := gitConfigValue("core.commentChar")
+	if char == "" {
+		char = gitConfigValue("core.commentString")
+	}
+	if char == "" {
+		return defaultCommentChar
+	}
+	if char != "auto" {
+		return char
+	}
+
+	for _, c := range autoCommentChars {
+		used := false
+		for _, line := range strings.Split(message, "\n") {
+			if strings.HasPrefix(strings.TrimLeft(line, " \t"), string(c)) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			return string(c)
+		}
+	}
+	return defaultCommentChar
+}
+
+// cleanupStripsComments 判断 git 提交时是否会删除注释行
+//
+// commit.cleanup 未设置或为 default 时，只有经过编辑器编辑的信息才会删除注释行。
+func cleanupStripsComments(editing bool) bool {
+	switch gitConfigValue("commit.cleanup") {
+	case "strip", "scissors":
+		return true
+	case "whitespace", "verbatim":
+		return false
+	}
+	return editing
+}
+
+// commentedLines 返回以注释字符开头、会被 git 删除的行
+func commentedLines(message, char string) []string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, char) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// warnStrippedLines 生成的信息中有会被 git 当作注释删除的行时给出提示
+func warnStrippedLines(message string, editing bool) {
+	if !cleanupStripsComments(editing) {
+		return
+	}
+	char := commentChar(message)
+	if lines := commentedLines(message, char); len(lines) > 0 {
+		fmt.Fprintln(stdout, tr("commit.comment_lines", char, strings.Join(lines, "\n")))
+	}
+}
+
+// commitWithEditor 将生成的信息写入文件并打开编辑器，由用户确认后提交
+//
+// 只有 git 会删除注释行时才写入说明注释，避免按 commit.cleanup 设置原样提交时把说明一起提交。
+func commitWithEditor(message string) {
+	path, err := gitPath(editMsgFileName)
+	if err != nil {
+		fail("err.git", err)
+	}
+
+	content := message + "\n"
+	if cleanupStripsComments(true) {
+		char := commentChar(message)
+		content += "\n" + char + " " + tr("commit.edit_instructions") + "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		fail("commit.edit_write", err)
+	}
+	defer os.Remove(path)
+
+	// 编辑器需要连接终端
+	cmd := exec.Command("git", gitCompatArgs(withPathspecs(withAuthor("commit", "-e", "-F", path)...))...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fail("err.git", err)
+	}
+}
+
+// committedMessage 返回最新提交的信息，即经过 git 清理后实际保存的内容
+func committedMessage() string {
+	return currentVCS.lastMessage()
+}
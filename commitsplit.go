@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSplitSuggestions 建议拆分时最多列出的目录数
+const maxSplitSuggestions = 5
+
+// commitArea 改动所在的一个顶层目录及其文件数
+type commitArea struct {
+	dir   string
+	files int
+}
+
+// commitAreas 按顶层目录统计改动的文件，根目录下的文件（go.mod、README 等）
+// 常常随任意改动一起修改，不算作单独的区域
+func commitAreas(files []string) []commitArea {
+	counts := make(map[string]int)
+	for _, path := range files {
+		if dir, _, ok := strings.Cut(path, "/"); ok {
+			counts[dir]++
+		}
+	}
+
+	areas := make([]commitArea, 0, len(counts))
+	for dir, n := range counts {
+		areas = append(areas, commitArea{dir: dir, files: n})
+	}
+	sort.Slice(areas, func(i, j int) bool {
+		if areas[i].files != areas[j].files {
+			return areas[i].files > areas[j].files
+		}
+		return areas[i].dir < areas[j].dir
+	})
+	return areas
+}
+
+// lastCommitAge 返回当前分支上一次提交距今的时间，无法获取时返回 0
+func lastCommitAge() time.Duration {
+	if currentVCS.name() != vcsGit {
+		return 0
+	}
+	output, err := gitOutput("log", "-1", "--format=%ct")
+	if err != nil {
+		return 0
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Since(time.Unix(seconds, 0))
+}
+
+// warnLargeCommit 改动的文件数超过 warn_large_commit_files 且分布在多个顶层目录时，
+// 提示拆分为多个提交，并列出只提交其中一个目录的命令
+//
+// 只是提示，不影响本次提交。cherry-pick、revert 等需要保持原提交的完整，不做提示。
+func warnLargeCommit(ctx promptContext) {
+	limit := config.WarnLargeCommitFiles
+	if limit <= 0 || ctx.sequencer != nil || len(ctx.files) <= limit {
+		return
+	}
+	areas := commitAreas(ctx.files)
+	if len(areas) < 2 {
+		return
+	}
+
+	dirs := make([]string, len(areas))
+	for i, a := range areas {
+		dirs[i] = a.dir + "/"
+	}
+	fmt.Fprintln(stderr, tr("split.warning", len(ctx.files), len(areas), strings.Join(dirs, ", ")))
+	// 很久没有提交时改动往往越积越多，一并提示
+	if age := lastCommitAge(); age >= 24*time.Hour {
+		fmt.Fprintln(stderr, tr("split.last_commit", int(age.Hours()/24)))
+	}
+
+	fmt.Fprintln(stderr, tr("split.suggestion"))
+	for i, a := range areas {
+		if i == maxSplitSuggestions {
+			fmt.Fprintln(stderr, tr("split.more", len(areas)-maxSplitSuggestions))
+			break
+		}
+		fmt.Fprintf(stderr, "  aicommit %s/  # %s\n", a.dir, tr("split.files", a.files))
+	}
+	fmt.Fprintln(stderr)
+}
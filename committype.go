@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// docExtensions 文档文件扩展名
+var docExtensions = []string{".md", ".markdown", ".rst", ".adoc", ".asciidoc", ".txt"}
+
+// docBaseNames 不带扩展名或扩展名不固定的常见文档文件名
+var docBaseNames = []string{"README", "CHANGELOG", "CHANGES", "CONTRIBUTING", "AUTHORS", "LICENSE", "NOTICE"}
+
+// docDirs 存放文档的常见目录名
+var docDirs = []string{"docs", "doc", "documentation"}
+
+// dependencyManifests 依赖清单和锁文件
+var dependencyManifests = []string{
+	"go.mod", "go.sum",
+	"package.json", "package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb",
+	"Cargo.toml", "Cargo.lock",
+	"requirements.txt", "Pipfile", "Pipfile.lock", "poetry.lock", "uv.lock",
+	"Gemfile", "Gemfile.lock",
+	"composer.json", "composer.lock",
+	"mix.exs", "mix.lock",
+	"pubspec.yaml", "pubspec.lock",
+	"Podfile", "Podfile.lock", "Package.resolved",
+}
+
+// isDocFile 根据路径判断是否为文档文件
+func isDocFile(file string) bool {
+	base := path.Base(file)
+	name := strings.ToUpper(strings.TrimSuffix(base, path.Ext(base)))
+	for _, doc := range docBaseNames {
+		if name == doc {
+			return true
+		}
+	}
+
+	ext := strings.ToLower(path.Ext(base))
+	for _, docExt := range docExtensions {
+		// requirements.txt 等 .txt 依赖清单不算文档
+		if ext == docExt && !isDependencyManifest(file) {
+			return true
+		}
+	}
+
+	for _, dir := range strings.Split(path.Dir(file), "/") {
+		for _, docDir := range docDirs {
+			if dir == docDir {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDependencyManifest 根据路径判断是否为依赖清单或锁文件
+func isDependencyManifest(file string) bool {
+	base := path.Base(file)
+	if strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt") {
+		return true
+	}
+	return containsString(dependencyManifests, base)
+}
+
+// detectCommitType 根据改动文件推断提交类型，无法明确判断时返回空
+//
+// 只在所有文件都属于同一类时给出结果：全是测试文件为 test，全是文档为 docs，
+// 全是依赖清单为 chore(deps)。
+func detectCommitType(files []string) (string, string) {
+	if len(files) == 0 {
+		return "", ""
+	}
+
+	all := func(match func(string) bool) bool {
+		for _, f := range files {
+			if !match(f) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case all(isDependencyManifest):
+		return "chore", "deps"
+	case all(isTestFile):
+		return "test", ""
+	case all(isDocFile):
+		return "docs", ""
+	}
+	return "", ""
+}
+
+// commitTypeHint 能明确推断提交类型时要求模型使用该类型
+func commitTypeHint(files []string) string {
+	typ, scope := detectCommitType(files)
+	if typ == "" {
+		return ""
+	}
+	if scope != "" {
+		return fmt.Sprintf("All changed files are dependency manifests or lock files. If the commit message uses Conventional Commits, the subject must start with \"%s(%s): \" and name the updated dependencies.", typ, scope)
+	}
+	return fmt.Sprintf("All changed files are %s. If the commit message uses Conventional Commits, its type must be \"%s\".", map[string]string{"test": "tests", "docs": "documentation"}[typ], typ)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compareCandidate 对比模式中的一组模型参数及其生成结果
+type compareCandidate struct {
+	settings chatSettings
+	label    string
+	message  string
+	tokens   int
+	err      error
+}
+
+func runCompare(args []string) {
+	var temps []float64
+	var models []string
+	lang := ""
+	notes := ""
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if value, ok := flagValue(args, &i, "--temps"); ok {
+			for _, item := range splitList(value) {
+				temp, err := strconv.ParseFloat(item, 64)
+				if err != nil || temp < 0 || temp > 2 {
+					fail("arg.invalid_temps", item)
+				}
+				temps = append(temps, temp)
+			}
+		} else if value, ok := flagValue(args, &i, "--models"); ok {
+			models = append(models, splitList(value)...)
+		} else if value, ok := flagValue(args, &i, "--lang"); ok {
+			lang = value
+		} else if value, ok := flagValue(args, &i, "--notes"); ok {
+			notes = value
+		} else {
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	if len(temps) == 0 && len(models) == 0 {
+		fail("compare.no_settings")
+	}
+
+	ensureGitRepository()
+
+	if err := loadConfig(); err != nil {
+		fail("err.load_config", err)
+	}
+	if lang != "" {
+		config.DefaultLang = lang
+	}
+	extraNotes = notes
+
+	if err := loadRepoConfig(); err != nil {
+		fail("err.load_repo_config", err)
+	}
+	applyRepoConfig()
+
+	// 对比模式不修改暂存区，只使用工作目录和暂存区中已跟踪文件的差异
+	diff := getGitDiff()
+	if diff == "" {
+		fmt.Fprintln(stdout, tr("commit.no_diff"))
+		os.Exit(0)
+	}
+
+	prepared := prepareDiff(diff)
+	ctx := newPromptContext(prepared)
+	if len(ctx.diff) > maxDiffChars() {
+		ctx.summarized = true
+		summary, err := summarizeDiff(prepared.files)
+		if err != nil {
+			failWith(err)
+		}
+		ctx.diff = summary
+	}
+
+	candidates := compareCandidates(temps, models)
+	fmt.Fprintln(stdout, tr("compare.start", len(candidates), maxParallel()))
+	generateCandidates(buildPrompt(ctx), candidates)
+	printCandidates(candidates)
+}
+
+// splitList 拆分逗号分隔的列表并去掉空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// compareCandidates 生成所有模型和温度的组合，未指定的一项使用配置中的值
+func compareCandidates(temps []float64, models []string) []*compareCandidate {
+	base := currentChatSettings()
+	if len(temps) == 0 {
+		temps = []float64{base.temperature}
+	}
+	if len(models) == 0 {
+		models = []string{base.model}
+	}
+
+	var candidates []*compareCandidate
+	for _, model := range models {
+		for _, temp := range temps {
+			candidates = append(candidates, &compareCandidate{
+				settings: chatSettings{model: model, temperature: temp},
+				label:    fmt.Sprintf("model=%s temperature=%g", model, temp),
+			})
+		}
+	}
+	return candidates
+}
+
+// generateCandidates 并发地为每组参数生成提交信息，并发数受 max_parallel 限制
+func generateCandidates(prompt chatPrompt, candidates []*compareCandidate) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallel())
+
+	for _, c := range candidates {
+		wg.Add(1)
+		go func(c *compareCandidate) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := requestPromptWith(prompt, c.settings)
+			c.err = err
+			c.tokens = result.usage.TotalTokens
+			c.message = applyGitmoji(applySubjectStyle(strings.TrimSpace(strings.Trim(result.content, `"`)), config.DefaultLang))
+		}(c)
+	}
+	wg.Wait()
+}
+
+// printCandidates 依次输出每组参数生成的提交信息
+func printCandidates(candidates []*compareCandidate) {
+	for i, c := range candidates {
+		fmt.Fprintln(stdout)
+		if plainOutput {
+			fmt.Fprintln(stdout, tr("compare.candidate", i+1, c.label))
+		} else {
+			fmt.Fprintf(stdout, "=== [%d] %s ===\n", i+1, c.label)
+		}
+		switch {
+		case c.err != nil:
+			fmt.Fprintln(stdout, tr("compare.failed", c.err))
+		case c.message == "":
+			fmt.Fprintln(stdout, tr("commit.unable"))
+		default:
+			fmt.Fprintln(stdout, c.message)
+			fmt.Fprintln(stdout, tr("compare.stats", len([]rune(messageSubject(c.message))), c.tokens))
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// bundleVersion 设置包的格式版本，格式不兼容地改变时加一
+//
+// 导入时拒绝版本更高的设置包，避免旧版本按错误的含义理解新增的内容。
+const bundleVersion = 1
+
+// secretKeys 导出时可以去掉的密钥类配置项，顶层配置和服务商配置中相同
+var secretKeys = []string{"api_key", "proxy_password"}
+
+// settingsBundle 在机器之间迁移或在团队中共享的设置包
+type settingsBundle struct {
+	Version    int                    `json:"version"`
+	ExportedAt string                 `json:"exported_at,omitempty"`
+	Config     map[string]interface{} `json:"config"`
+	Templates  map[string]string      `json:"templates,omitempty"`
+	// Redacted 导出时去掉的密钥，导入时提示在新机器上另行设置
+	Redacted []string `json:"redacted,omitempty"`
+}
+
+// runConfigExport 将用户配置和覆盖的提示词模板导出为设置包
+func runConfigExport(args []string) {
+	noSecrets := false
+	output := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if arg == "--no-secrets" {
+			noSecrets = true
+		} else if value, ok := flagValue(args, &i, "--output"); ok {
+			output = value
+		} else {
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		fail("err.load_config", err)
+	}
+	settings, err := readConfigMap(configPath)
+	if err != nil {
+		fail("bundle.read_failed", configPath, err)
+	}
+
+	bundle := settingsBundle{
+		Version:    bundleVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Config:     settings,
+		Templates:  overriddenTemplates(),
+	}
+	if noSecrets {
+		bundle.Redacted = redactSecrets(settings, "")
+		sort.Strings(bundle.Redacted)
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		fail("bundle.write_failed", output, err)
+	}
+	data = append(data, '\n')
+	if output == "" || output == "-" {
+		stdout.Write(data)
+		return
+	}
+	// 未去掉密钥的设置包与配置文件一样只允许本人读取
+	if err := os.WriteFile(output, data, 0600); err != nil {
+		fail("bundle.write_failed", output, err)
+	}
+	fmt.Fprintln(stderr, tr("bundle.exported", output, len(bundle.Templates)))
+	if !noSecrets && containsSecrets(settings) {
+		fmt.Fprintln(stderr, tr("bundle.contains_secrets"))
+	}
+}
+
+// runConfigImport 将设置包合并到用户配置中
+//
+// 设置包中的配置项覆盖本地的同名配置项，对象逐项合并，数组整体替换；设置包中没有的
+// 配置项（包括导出时去掉的密钥）保留本地的值。合并后的配置校验通过才会写入，
+// 原来的配置文件备份为 config.json.bak。
+func runConfigImport(args []string) {
+	dryRun := false
+	file := ""
+	for _, arg := range args {
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if arg == "--dry-run" {
+			dryRun = true
+		} else if (strings.HasPrefix(arg, "-") && arg != "-") || file != "" {
+			failUsage("arg.unknown", arg)
+		} else {
+			file = arg
+		}
+	}
+	if file == "" {
+		failUsage("bundle.missing_file")
+	}
+
+	bundle := readSettingsBundle(file)
+
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		fail("err.load_config", err)
+	}
+	current := map[string]interface{}{}
+	original, err := readConfigFile(configPath)
+	if err == nil {
+		if current, err = readConfigMap(configPath); err != nil {
+			fail("bundle.read_failed", configPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		fail("bundle.read_failed", configPath, err)
+	}
+
+	missing := keepLocalSecrets(bundle, current)
+	var changes []string
+	mergeSettings(current, bundle.Config, "", &changes)
+	sort.Strings(changes)
+
+	merged, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		fail("bundle.write_failed", configPath, err)
+	}
+	merged = append(merged, '\n')
+	if _, err := checkConfigFile(configPath, merged); err != nil {
+		fail("bundle.invalid_config", strings.TrimPrefix(err.Error(), "\n"))
+	}
+
+	local := overriddenTemplates()
+	templateNames := make([]string, 0, len(bundle.Templates))
+	for name, text := range bundle.Templates {
+		if !containsString(defaultTemplateNames(), name) {
+			fmt.Fprintln(stderr, tr("bundle.unknown_template", name))
+			continue
+		}
+		if existing, ok := local[name]; !ok || existing != text {
+			templateNames = append(templateNames, name)
+		}
+	}
+	sort.Strings(templateNames)
+
+	if len(changes) == 0 && len(templateNames) == 0 {
+		fmt.Fprintln(stdout, tr("bundle.no_changes"))
+	}
+	for _, key := range changes {
+		fmt.Fprintln(stdout, "  "+key)
+	}
+	for _, name := range templateNames {
+		fmt.Fprintln(stdout, "  "+tr("bundle.template", name))
+	}
+	if dryRun {
+		fmt.Fprintln(stdout, tr("bundle.dry_run"))
+		return
+	}
+
+	if len(changes) > 0 {
+		if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+			fail("bundle.write_failed", configPath, err)
+		}
+		if original != nil {
+			if err := os.WriteFile(configPath+".bak", original, 0600); err != nil {
+				fail("bundle.write_failed", configPath+".bak", err)
+			}
+		}
+		if err := os.WriteFile(configPath, merged, 0600); err != nil {
+			fail("bundle.write_failed", configPath, err)
+		}
+	}
+	if len(templateNames) > 0 {
+		dir, err := templatesDir()
+		if err != nil {
+			fail("err.load_config", err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fail("template.write_failed", dir, err)
+		}
+		for _, name := range templateNames {
+			path := filepath.Join(dir, name+templateExt)
+			if err := os.WriteFile(path, []byte(bundle.Templates[name]), 0644); err != nil {
+				fail("template.write_failed", path, err)
+			}
+		}
+	}
+	fmt.Fprintln(stdout, tr("bundle.imported", len(changes), len(templateNames), configPath))
+	if len(changes) > 0 && original != nil {
+		fmt.Fprintln(stdout, tr("bundle.backup", configPath+".bak"))
+	}
+
+	if len(missing) > 0 {
+		fmt.Fprintln(stdout, tr("bundle.missing_secrets", strings.Join(missing, ", ")))
+	}
+}
+
+// keepLocalSecrets 导出时去掉的密钥保留本地的值，返回本地也没有、需要另行设置的密钥
+//
+// 去掉认证信息的 proxy_url 在本地有设置时同样保留本地的值，避免覆盖本地的代理密码。
+func keepLocalSecrets(bundle settingsBundle, current map[string]interface{}) []string {
+	var missing []string
+	for _, key := range bundle.Redacted {
+		if _, ok := lookupSetting(current, key); ok {
+			deleteSetting(bundle.Config, key)
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// readConfigMap 按原样读取配置文件中的配置项，不填充默认值
+func readConfigMap(path string) (map[string]interface{}, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]interface{}{}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// readSettingsBundle 读取设置包，file 为 - 时从标准输入读取
+func readSettingsBundle(file string) settingsBundle {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = readConfigFile(file)
+	}
+	if err != nil {
+		fail("bundle.read_failed", file, err)
+	}
+
+	var bundle settingsBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		fail("bundle.invalid", file, err)
+	}
+	if bundle.Version == 0 || bundle.Config == nil {
+		fail("bundle.invalid", file, tr("bundle.not_bundle"))
+	}
+	if bundle.Version > bundleVersion {
+		fail("bundle.newer", file, bundle.Version, bundleVersion)
+	}
+	return bundle
+}
+
+// overriddenTemplates 返回用户模板目录中覆盖了内置模板的模板
+func overriddenTemplates() map[string]string {
+	dir, err := templatesDir()
+	if err != nil {
+		return nil
+	}
+	templates := make(map[string]string)
+	for _, name := range defaultTemplateNames() {
+		if data, err := readConfigFile(filepath.Join(dir, name+templateExt)); err == nil {
+			templates[name] = string(data)
+		}
+	}
+	return templates
+}
+
+// redactSecrets 去掉顶层配置和服务商配置中的密钥，返回去掉的配置项
+//
+// 代理 URL 中的认证信息同样去掉，只保留地址。
+func redactSecrets(settings map[string]interface{}, prefix string) []string {
+	var redacted []string
+	for _, key := range secretKeys {
+		if value, ok := settings[key].(string); ok && value != "" {
+			delete(settings, key)
+			redacted = append(redacted, prefix+key)
+		}
+	}
+	if rawURL, ok := settings["proxy_url"].(string); ok {
+		if u, err := url.Parse(rawURL); err == nil && u.User != nil {
+			u.User = nil
+			settings["proxy_url"] = u.String()
+			redacted = append(redacted, prefix+"proxy_url")
+		}
+	}
+	if prefix == "" {
+		if profiles, ok := settings["profiles"].(map[string]interface{}); ok {
+			for name, p := range profiles {
+				if profile, ok := p.(map[string]interface{}); ok {
+					redacted = append(redacted, redactSecrets(profile, "profiles."+name+".")...)
+				}
+			}
+		}
+	}
+	return redacted
+}
+
+// containsSecrets 判断配置中是否有密钥
+func containsSecrets(settings map[string]interface{}) bool {
+	copied := map[string]interface{}{}
+	data, _ := json.Marshal(settings)
+	json.Unmarshal(data, &copied)
+	return len(redactSecrets(copied, "")) > 0
+}
+
+// mergeSettings 将 src 合并到 dst 中，记录新增或改变的配置项
+func mergeSettings(dst, src map[string]interface{}, prefix string, changes *[]string) {
+	for key, value := range src {
+		path := prefix + key
+		if srcMap, ok := value.(map[string]interface{}); ok {
+			if dstMap, ok := dst[key].(map[string]interface{}); ok {
+				mergeSettings(dstMap, srcMap, path+".", changes)
+				continue
+			}
+		}
+		old, exists := dst[key]
+		if !exists {
+			*changes = append(*changes, "+ "+path)
+		} else if !jsonEqual(old, value) {
+			*changes = append(*changes, "~ "+path)
+		} else {
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// jsonEqual 比较两个 JSON 值是否相同
+func jsonEqual(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+// lookupSetting 按 profiles.openai.api_key 形式的路径查找配置项，值为空时视为不存在
+func lookupSetting(settings map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = settings
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return nil, false
+	}
+	return value, true
+}
+
+// deleteSetting 按路径删除配置项
+func deleteSetting(settings map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := settings[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		settings = child
+	}
+	delete(settings, keys[len(keys)-1])
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] == "--help" || args[0] == "-h" {
+		printHelp()
+		os.Exit(0)
+	}
+
+	switch args[0] {
+	case "validate":
+		runConfigValidate(args[1:])
+	case "templates":
+		runConfigTemplates(args[1:])
+	case "export":
+		runConfigExport(args[1:])
+	case "import":
+		runConfigImport(args[1:])
+	default:
+		failUsage("arg.unknown", args[0])
+	}
+}
+
+// runConfigValidate 校验配置文件，未指定文件时校验用户配置和当前仓库的仓库级配置
+func runConfigValidate(args []string) {
+	var files []string
+	for _, arg := range args {
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		}
+		if strings.HasPrefix(arg, "-") {
+			failUsage("arg.unknown", arg)
+		}
+		files = append(files, arg)
+	}
+
+	if len(files) == 0 {
+		configPath, err := getConfigFilePath()
+		if err != nil {
+			fail("err.load_config", err)
+		}
+		files = append(files, configPath)
+		if repoPath, err := getRepoConfigFilePath(); err == nil {
+			if _, err := os.Stat(repoPath); err == nil {
+				files = append(files, repoPath)
+			}
+		}
+	}
+
+	failed := false
+	for _, file := range files {
+		data, err := readConfigFile(file)
+		if err != nil {
+			fmt.Fprintln(stdout, tr("config.read_failed", err))
+			failed = true
+			continue
+		}
+
+		if filepath.Base(file) == repoConfigFileName {
+			_, err = checkRepoConfigFile(file, data)
+		} else {
+			_, err = checkConfigFile(file, data)
+		}
+		if err != nil {
+			fmt.Fprintln(stdout, strings.TrimPrefix(err.Error(), "\n"))
+			failed = true
+			continue
+		}
+		fmt.Fprintln(stdout, tr("config.valid", file))
+	}
+
+	if failed {
+		fail("config.invalid")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"sync"
+)
+
+// consoleWriter 标准输出或标准错误的写入端，可以暂存写入的内容
+//
+// 除了分页器、编辑器等需要连接终端的子进程，所有输出都经过 stdout 和 stderr。分页器占用终端时
+// 暂存后台任务的输出，分页器退出后再按写入的顺序输出到终端。暂存和恢复都在同一把锁内完成，
+// 不需要替换 os.Stdout 等全局变量。
+type consoleWriter struct {
+	file *os.File
+}
+
+// heldWrite 暂存的一次写入
+type heldWrite struct {
+	file *os.File
+	data []byte
+}
+
+var (
+	stdout = &consoleWriter{file: os.Stdout}
+	stderr = &consoleWriter{file: os.Stderr}
+
+	consoleMu   sync.Mutex
+	consoleHeld bool
+	heldWrites  []heldWrite
+)
+
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+	if consoleHeld {
+		heldWrites = append(heldWrites, heldWrite{file: c.file, data: append([]byte(nil), p...)})
+		return len(p), nil
+	}
+	return c.file.Write(p)
+}
+
+// holdConsole 暂存标准输出和标准错误，返回恢复的函数，恢复时输出暂存的内容
+func holdConsole() func() {
+	consoleMu.Lock()
+	consoleHeld = true
+	consoleMu.Unlock()
+
+	return func() {
+		consoleMu.Lock()
+		defer consoleMu.Unlock()
+		for _, w := range heldWrites {
+			w.file.Write(w.data)
+		}
+		heldWrites = nil
+		consoleHeld = false
+	}
+}
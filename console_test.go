@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestHoldConsole(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	out, errOut := &consoleWriter{file: f}, &consoleWriter{file: f}
+
+	release := holdConsole()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fmt.Fprintln(out, "one")
+		fmt.Fprintln(errOut, "two")
+		fmt.Fprintln(out, "three")
+	}()
+	wg.Wait()
+
+	if data, _ := os.ReadFile(f.Name()); len(data) != 0 {
+		t.Fatalf("output written while held: %q", data)
+	}
+	release()
+	fmt.Fprintln(errOut, "four")
+
+	// 暂存的内容按写入的顺序输出，之后的输出直接写入
+	data, _ := os.ReadFile(f.Name())
+	if want := "one\ntwo\nthree\nfour\n"; string(data) != want {
+		t.Errorf("output = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	daemonSocketName = "daemon.sock"
+
+	// daemonDisableEnv 设置后不使用守护进程，直接发送请求
+	daemonDisableEnv = "AICOMMIT_NO_DAEMON"
+
+	// daemonDialTimeout 连接守护进程的超时时间，守护进程未运行时应尽快回退为直接请求
+	daemonDialTimeout = 200 * time.Millisecond
+
+	// daemonIdleTimeout 守护进程中空闲连接的保留时间
+	daemonIdleTimeout = 10 * time.Minute
+)
+
+// daemonRequest CLI 发给守护进程的请求
+type daemonRequest struct {
+	Action   string            `json:"action,omitempty"`
+	Method   string            `json:"method,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Header   map[string]string `json:"header,omitempty"`
+	Body     []byte            `json:"body,omitempty"`
+	Timeout  int               `json:"timeout,omitempty"`
+	ProxyKey string            `json:"proxy_key,omitempty"`
+}
+
+// daemonResponse 守护进程返回的响应
+type daemonResponse struct {
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+	Error       string `json:"error,omitempty"`
+
+	// 状态查询的结果
+	PID      int    `json:"pid,omitempty"`
+	Started  string `json:"started,omitempty"`
+	Requests int    `json:"requests,omitempty"`
+}
+
+var (
+	// errDaemonMismatch 守护进程的代理设置与本次运行不一致，需要直接发送请求
+	errDaemonMismatch = errors.New("daemon proxy settings differ")
+
+	// errDaemonUnavailable 无法连接守护进程，请求尚未发出，可以直接发送
+	errDaemonUnavailable = errors.New("daemon not running")
+)
+
+// daemonSocketPath 守护进程的 unix socket 路径，与配置文件在同一目录
+func daemonSocketPath() (string, error) {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), daemonSocketName), nil
+}
+
+// proxyKey 代理设置的摘要，守护进程只转发代理设置相同的请求
+func proxyKey() string {
+	parts := []string{config.ProxyURL, config.ProxyUsername, config.ProxyPassword, fmt.Sprint(proxyFromEnv())}
+	if proxyFromEnv() {
+		for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy"} {
+			parts = append(parts, os.Getenv(name))
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func runDaemon(args []string) {
+	action := ""
+	for _, arg := range args {
+		switch arg {
+		case "--help", "-h":
+			printHelp()
+			os.Exit(0)
+		case "stop", "status":
+			action = arg
+		default:
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	socket, err := daemonSocketPath()
+	if err != nil {
+		fail("err.load_config", err)
+	}
+
+	switch action {
+	case "stop":
+		if _, err := callDaemon(socket, daemonRequest{Action: "stop"}, time.Second); err != nil {
+			fail("daemon.not_running", socket)
+		}
+		fmt.Fprintln(stdout, tr("daemon.stopped"))
+	case "status":
+		resp, err := callDaemon(socket, daemonRequest{Action: "status"}, time.Second)
+		if err != nil {
+			fail("daemon.not_running", socket)
+		}
+		fmt.Fprintln(stdout, tr("daemon.status", resp.PID, resp.Started, resp.Requests, socket))
+	default:
+		serveDaemon(socket)
+	}
+}
+
+// serveDaemon 在前台运行守护进程，直到收到 stop 请求或信号
+//
+// 守护进程保持已加载的配置和与 API 的 keep-alive 连接，CLI 通过 unix socket 转发请求，
+// 省去每次运行时的 TCP 和 TLS 握手。能力检测结果、端点健康状态等仍由 CLI 读写各自的
+// 文件，守护进程不在内存中保存这些状态。
+func serveDaemon(socket string) {
+	if err := loadConfig(); err != nil {
+		fail("err.load_config", err)
+	}
+
+	// 已有守护进程在运行时不再启动；socket 文件残留时删除
+	if _, err := callDaemon(socket, daemonRequest{Action: "status"}, time.Second); err == nil {
+		fail("daemon.already_running", socket)
+	}
+	os.Remove(socket)
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		fail("daemon.listen_failed", socket, err)
+	}
+	defer os.Remove(socket)
+	// socket 中会传输 API 密钥，只允许当前用户访问
+	os.Chmod(socket, 0600)
+
+	client, err := newHTTPClient(0)
+	if err != nil {
+		fail("api.client", err)
+	}
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		transport.IdleConnTimeout = daemonIdleTimeout
+	}
+
+	d := &daemon{
+		client:   client,
+		proxyKey: proxyKey(),
+		started:  time.Now(),
+		stop:     make(chan struct{}),
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+		case <-d.stop:
+		}
+		listener.Close()
+	}()
+
+	fmt.Fprintln(stdout, tr("daemon.listening", socket))
+	for _, endpoint := range configuredEndpoints() {
+		go d.warm(endpoint)
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			break
+		}
+		go d.handle(conn)
+	}
+	fmt.Fprintln(stdout, tr("daemon.stopped"))
+}
+
+// daemon 守护进程的状态
+type daemon struct {
+	client   *http.Client
+	proxyKey string
+	started  time.Time
+	requests atomic.Int64
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// warm 预先与 API 服务器建立连接，使第一次请求也无需等待握手
+func (d *daemon) warm(endpoint string) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return
+	}
+	req, err := http.NewRequest("HEAD", u.Scheme+"://"+u.Host+"/", nil)
+	if err != nil {
+		return
+	}
+	if resp, err := d.client.Do(req); err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// handle 处理一个 CLI 连接
+func (d *daemon) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var req daemonRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	var resp daemonResponse
+	switch req.Action {
+	case "status":
+		resp = daemonResponse{PID: os.Getpid(), Started: d.started.Format(time.RFC3339), Requests: int(d.requests.Load())}
+	case "stop":
+		d.stopOnce.Do(func() { close(d.stop) })
+	default:
+		resp = d.forward(req)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// forward 使用保持的连接发送 CLI 转发的请求
+func (d *daemon) forward(req daemonRequest) daemonResponse {
+	if req.ProxyKey != d.proxyKey {
+		return daemonResponse{Error: errDaemonMismatch.Error()}
+	}
+	d.requests.Add(1)
+
+	ctx := context.Background()
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return daemonResponse{Error: err.Error()}
+	}
+	for key, value := range req.Header {
+		httpReq.Header.Set(key, value)
+	}
+
+	httpResp, err := d.client.Do(httpReq)
+	if err != nil {
+		return daemonResponse{Error: err.Error()}
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return daemonResponse{Error: err.Error()}
+	}
+	return daemonResponse{Status: httpResp.StatusCode, ContentType: httpResp.Header.Get("Content-Type"), Body: body}
+}
+
+// callDaemon 向守护进程发送请求并等待响应
+func callDaemon(socket string, req daemonRequest, timeout time.Duration) (daemonResponse, error) {
+	conn, err := net.DialTimeout("unix", socket, daemonDialTimeout)
+	if err != nil {
+		return daemonResponse{}, fmt.Errorf("%w: %v", errDaemonUnavailable, err)
+	}
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return daemonResponse{}, err
+	}
+	var resp daemonResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return daemonResponse{}, err
+	}
+	return resp, nil
+}
+
+// daemonTransport 通过守护进程发送请求，守护进程不可用时直接发送
+type daemonTransport struct {
+	socket   string
+	timeout  time.Duration
+	fallback http.RoundTripper
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *daemonTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+
+	header := make(map[string]string, len(req.Header))
+	for key := range req.Header {
+		header[key] = req.Header.Get(key)
+	}
+
+	timeout := t.timeout
+	if deadline, ok := req.Context().Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	resp, err := callDaemon(t.socket, daemonRequest{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Header:   header,
+		Body:     body,
+		Timeout:  int(t.timeout / time.Second),
+		ProxyKey: proxyKey(),
+	}, timeout)
+	// 只在守护进程未运行或代理设置不同时直接发送；连接后出错（如等待响应超时）时
+	// 守护进程可能已经发出了请求，再直接发送会重复一次收费的调用
+	if errors.Is(err, errDaemonUnavailable) || err == nil && resp.Error == errDaemonMismatch.Error() {
+		retry := req.Clone(req.Context())
+		retry.Body = io.NopCloser(bytes.NewReader(body))
+		return t.fallback.RoundTrip(retry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	return &http.Response{
+		StatusCode: resp.Status,
+		Status:     fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		Header:     http.Header{"Content-Type": []string{resp.ContentType}},
+		Body:       io.NopCloser(bytes.NewReader(resp.Body)),
+		Request:    req,
+	}, nil
+}
+
+// useDaemon 守护进程在运行时让客户端通过它发送请求
+//
+// 离线模式下不使用守护进程，守护进程可能不是以离线模式启动的。
+func useDaemon(client *http.Client, timeout time.Duration) {
+	if os.Getenv(daemonDisableEnv) != "" || offlineMode() {
+		return
+	}
+	socket, err := daemonSocketPath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(socket); err != nil {
+		return
+	}
+	client.Transport = &daemonTransport{socket: socket, timeout: timeout, fallback: client.Transport}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const dailyContextFileName = "daily-context.json"
+
+// dailyContext 当天的工作目标，每个仓库一份，只保存在本地
+type dailyContext struct {
+	Date string `json:"date"`
+	Goal string `json:"goal"`
+}
+
+// today 返回本地时间的日期
+func today() string {
+	return time.Now().Format("2006-01-02")
+}
+
+// loadDailyContext 读取当前仓库记录的工作目标
+func loadDailyContext() dailyContext {
+	var c dailyContext
+	path, err := repoStatePath(dailyContextFileName)
+	if err != nil {
+		return c
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &c)
+	}
+	return c
+}
+
+// saveDailyContext 保存今天的工作目标，跳过时也保存空目标，当天不再询问
+func saveDailyContext(goal string) {
+	path, err := repoStatePath(dailyContextFileName)
+	if err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(dailyContext{Date: today(), Goal: goal}, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// todayGoal 返回今天记录的工作目标，没有时返回空
+func todayGoal() string {
+	if c := loadDailyContext(); c.Date == today() {
+		return c.Goal
+	}
+	return ""
+}
+
+// dailyGoal 返回今天的工作目标，goal 不为 nil 时使用 --goal 指定的目标
+//
+// 开启 daily_context 时，每个仓库每天第一次生成提交信息前在终端中询问一次，
+// 直接回车跳过，当天不再询问。标准输入不是终端时（如钩子、CI）不询问。
+func dailyGoal(goal *string) string {
+	if goal != nil {
+		text := strings.TrimSpace(*goal)
+		saveDailyContext(text)
+		if text != "" {
+			fmt.Fprintln(stdout, tr("daily.saved"))
+		}
+		return text
+	}
+
+	if c := loadDailyContext(); c.Date == today() {
+		return c.Goal
+	}
+	if !config.DailyContext || !isTerminal(os.Stdin) {
+		return ""
+	}
+
+	fmt.Fprint(stdout, tr("daily.prompt"))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		// 没有输入时下次再问
+		fmt.Fprintln(stdout)
+		return ""
+	}
+	text := strings.TrimSpace(answer)
+	saveDailyContext(text)
+	return text
+}
+
+// dailyGoalHint 将当天的工作目标告知模型，帮助判断改动的意图
+func dailyGoalHint(goal string) string {
+	if goal == "" {
+		return ""
+	}
+	return fmt.Sprintf("Today the author is working on: %q. Use it to understand the intent of the changes, but describe only what this diff actually does.", goal)
+}
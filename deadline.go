@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// generationDeadline 生成提交信息的截止时间，零值表示不限制
+var generationDeadline time.Time
+
+// errDeadlineExceeded 超过 --deadline 时返回，返回的结果中可能带有已接收的部分内容
+var errDeadlineExceeded = errors.New("deadline exceeded")
+
+// partialSubject 从流式接收的部分内容中取出完整的标题行，标题未接收完时返回空
+func partialSubject(partial string) string {
+	end := strings.IndexByte(partial, '\n')
+	if end < 0 {
+		return ""
+	}
+
+	subject := strings.TrimSpace(partial[:end])
+	subject = strings.TrimPrefix(subject, `"`)
+	subject = strings.TrimSuffix(subject, `"`)
+	return strings.TrimSpace(subject)
+}
+
+// heuristicMessage 无法及时得到模型结果或走快速路径时，根据改动文件在本地生成提交标题
+func heuristicMessage(ctx promptContext) string {
+	typ, scope := detectCommitType(ctx.files)
+	if typ == "" {
+		typ = "chore"
+	}
+
+	verb := "update"
+	if len(ctx.newFiles) > 0 && len(ctx.newFiles) == len(ctx.files) {
+		verb = "add"
+	}
+
+	target := ""
+	switch n := len(ctx.files); {
+	case n == 0:
+		target = "files"
+	case n <= 3:
+		target = strings.Join(ctx.files, ", ")
+	default:
+		target = fmt.Sprintf("%d files", n)
+	}
+
+	return fmt.Sprintf("%s: %s %s", conventionalPrefix(typ, scope, ctx.files), verb, target)
+}
+
+// conventionalPrefix 返回本地生成标题的 "<type>(<scope>)" 前缀，没有指定 scope 时根据改动文件推导
+func conventionalPrefix(typ, scope string, files []string) string {
+	if scope == "" && (config.ScopeSource != "" || len(config.ScopeMap) > 0) {
+		if scopes := inferScopes(files); len(scopes) == 1 {
+			scope = scopes[0]
+		}
+	}
+	if scope != "" {
+		return typ + "(" + scope + ")"
+	}
+	return typ
+}
+
+// deadlineFallback 超过截止时间后选择可用的提交信息：优先使用已完整接收的标题，否则使用本地推断的标题
+func deadlineFallback(ctx promptContext, partial string) string {
+	if subject := partialSubject(partial); subject != "" {
+		fmt.Fprintln(stdout, tr("deadline.partial"))
+		return subject
+	}
+
+	fmt.Fprintln(stdout, tr("deadline.heuristic"))
+	return heuristicMessage(ctx)
+}
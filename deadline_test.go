@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestPartialSubject(t *testing.T) {
+	tests := []struct {
+		partial string
+		want    string
+	}{
+		// 标题行还没有接收完
+		{"", ""},
+		{"feat: add log", ""},
+		{"feat(api): add pagination to list endpoints", ""},
+		// 标题行已完整
+		{"feat: add login page\n", "feat: add login page"},
+		{"feat: add login page\n\nAdds the", "feat: add login page"},
+		{"  fix: handle nil config  \nbody", "fix: handle nil config"},
+		{"\"chore: bump deps\"\n", "chore: bump deps"},
+		{"\" docs: fix typo \"\r\n", "docs: fix typo"},
+		{"\n\nfeat: late start", ""},
+	}
+	for _, tt := range tests {
+		if got := partialSubject(tt.partial); got != tt.want {
+			t.Errorf("partialSubject(%q) = %q, want %q", tt.partial, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultDedupHistory = 10
+	maxDedupRetries     = 2
+	duplicateSimilarity = 0.85
+)
+
+// dedupHistory 参与去重比较的最近提交数，负数表示关闭去重
+func dedupHistory() int {
+	if config.DedupHistory == 0 {
+		return defaultDedupHistory
+	}
+	return config.DedupHistory
+}
+
+// getRecentSubjects 获取最近 n 个提交的标题
+func getRecentSubjects(n int) []string {
+	return currentVCS.recentSubjects(n)
+}
+
+// messageSubject 返回提交信息的标题行
+func messageSubject(message string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(message), "\n", 2)[0])
+}
+
+// normalizeSubject 规范化标题用于比较
+func normalizeSubject(subject string) string {
+	subject = strings.ToLower(strings.Join(strings.Fields(subject), " "))
+	return strings.TrimRight(subject, ".!。 ")
+}
+
+// isGenericSubject 判断标题是否过于笼统，例如 "Update code"
+func isGenericSubject(subject string) bool {
+	s := normalizeSubject(subject)
+	// 去掉 Conventional Commits 前缀后再判断
+	if conventionalPattern.MatchString(s) {
+		s = strings.TrimSpace(s[strings.Index(s, ":")+1:])
+	}
+	for _, generic := range lowQualitySubjects {
+		if s == generic {
+			return true
+		}
+	}
+	return false
+}
+
+// findDuplicateSubject 判断标题是否与最近的提交标题重复，返回重复的标题
+func findDuplicateSubject(subject string, recent []string) (string, bool) {
+	s := normalizeSubject(subject)
+	for _, r := range recent {
+		if similarity(s, normalizeSubject(r)) >= duplicateSimilarity {
+			return r, true
+		}
+	}
+	if isGenericSubject(subject) {
+		return subject, true
+	}
+	return "", false
+}
+
+// similarity 基于编辑距离计算两个字符串的相似度 (0-1)
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// appendUnique 追加不重复的元素
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// dedupHint 要求模型避免重复的标题
+func dedupHint(avoid []string) string {
+	if len(avoid) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, len(avoid))
+	for i, s := range avoid {
+		quoted[i] = strconv.Quote(s)
+	}
+	return fmt.Sprintf("The subject line must be specific about what changed and must not repeat or closely resemble any of these subjects: %s.", strings.Join(quoted, ", "))
+}
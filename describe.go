@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// describeRecentCommits 描述进行中的工作时作为背景的最近提交数
+const describeRecentCommits = 5
+
+func runDescribe(args []string) {
+	lang := ""
+	notes := ""
+	appendPath := ""
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if arg == "--" {
+			pathspecs = append(pathspecs, args[i+1:]...)
+			break
+		} else if value, ok := flagValue(args, &i, "--lang"); ok {
+			lang = value
+		} else if value, ok := flagValue(args, &i, "--notes"); ok {
+			notes = value
+		} else if value, ok := flagValue(args, &i, "--append"); ok {
+			appendPath = value
+		} else if strings.HasPrefix(arg, "-") {
+			failUsage("arg.unknown", arg)
+		} else {
+			pathspecs = append(pathspecs, arg)
+		}
+	}
+
+	ensureRepository()
+
+	if err := loadConfig(); err != nil {
+		fail("err.load_config", err)
+	}
+	applyVCSConfig()
+	if lang == "" {
+		lang = config.DefaultLang
+	}
+	if err := loadRepoConfig(); err != nil {
+		fail("err.load_repo_config", err)
+	}
+	applyRepoConfig()
+
+	// 不暂存任何文件，git 中未跟踪的文件单独生成差异
+	diff := currentVCS.diff()
+	if currentVCS.name() == vcsGit {
+		diff += untrackedDiff()
+	}
+	if diff == "" {
+		fmt.Fprintln(stdout, tr("commit.no_diff"))
+		os.Exit(0)
+	}
+
+	prepared := prepareDiff(diff)
+	data := promptData{
+		Diff:          prepared.text,
+		Stat:          diffStatPreamble(),
+		Lang:          lang,
+		Notes:         strings.TrimSpace(notes),
+		Branch:        currentBranch(),
+		RecentCommits: getRecentSubjects(describeRecentCommits),
+		Files:         currentVCS.changedFiles(),
+	}
+
+	summarized := false
+	if len(prepared.text) > maxDiffChars() {
+		summary, err := summarizeDiff(prepared.files)
+		if err != nil {
+			failWith(err)
+		}
+		data.Diff = summary
+		summarized = true
+	}
+	for _, hint := range []string{
+		newFilesHint(prepared.newFiles),
+		condensedFilesHint(prepared.condensed),
+		skippedFilesHint(prepared.skipped),
+		generatedFilesHint(prepared.generated),
+		vendoredDirsHint(prepared.vendored),
+		dailyGoalHint(todayGoal()),
+		structuralFilesHint(prepared.structural),
+		summarizedHint(summarized),
+	} {
+		if hint != "" {
+			data.Hints = append(data.Hints, hint)
+		}
+	}
+
+	fmt.Fprintln(stderr, tr("describe.generating", len(prepared.files)))
+	summary, err := requestCompletion(renderTemplate("describe", data))
+	if err != nil {
+		failWith(err)
+	}
+	if summary == "" {
+		fail("describe.unable")
+	}
+
+	if appendPath == "" {
+		fmt.Fprintln(stdout, summary)
+		return
+	}
+	if err := appendDescription(appendPath, summary, data.Branch); err != nil {
+		fail("describe.append_failed", appendPath, err)
+	}
+	fmt.Fprintln(stdout, tr("describe.appended", appendPath))
+}
+
+// untrackedDiff 生成未跟踪文件的差异，不修改暂存区
+//
+// 直接读取文件内容拼出新增文件的差异，不依赖 git diff --no-index 和 /dev/null，
+// Windows 上同样可用。
+func untrackedDiff() string {
+	root, err := currentVCS.root()
+	if err != nil {
+		return ""
+	}
+	output, err := gitOutput(withPathspecs("ls-files", "--others", "--exclude-standard", "--full-name")...)
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, file := range splitLines(output) {
+		b.WriteString(newFileDiff(root, file))
+	}
+	return b.String()
+}
+
+// newFileDiff 生成与 git diff 格式相同的新增文件差异，二进制文件只输出一行说明
+func newFileDiff(root, file string) string {
+	path := filepath.Join(root, filepath.FromSlash(file))
+	info, err := os.Lstat(path)
+	if err != nil {
+		return ""
+	}
+
+	mode := "100644"
+	var content []byte
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		mode = "120000"
+		target, err := os.Readlink(path)
+		if err != nil {
+			return ""
+		}
+		content = []byte(filepath.ToSlash(target))
+	case info.Mode().IsRegular():
+		if info.Mode()&0111 != 0 {
+			mode = "100755"
+		}
+		if content, err = os.ReadFile(path); err != nil {
+			return ""
+		}
+	default:
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\nnew file mode %s\n", file, file, mode)
+	if len(content) == 0 {
+		return b.String()
+	}
+	// 与 git 相同，前 8000 个字节中出现 NUL 时视为二进制文件
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		fmt.Fprintf(&b, "Binary files /dev/null and b/%s differ\n", file)
+		return b.String()
+	}
+
+	text := string(content)
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	fmt.Fprintf(&b, "--- /dev/null\n+++ b/%s\n@@ -0,0 +1", file)
+	if len(lines) != 1 {
+		fmt.Fprintf(&b, ",%d", len(lines))
+	}
+	b.WriteString(" @@\n")
+	for _, line := range lines {
+		b.WriteString("+" + line)
+	}
+	if !strings.HasSuffix(text, "\n") {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+	return b.String()
+}
+
+// appendDescription 将摘要追加到笔记文件，每条以时间和分支作为标题
+func appendDescription(path, summary, branch string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	heading := time.Now().Format("2006-01-02 15:04")
+	if branch != "" {
+		heading += " (" + branch + ")"
+	}
+	_, err = fmt.Fprintf(f, "## %s\n\n%s\n\n", heading, summary)
+	return err
+}
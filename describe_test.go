@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFileDiff(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		file    string
+		content string
+		want    string
+	}{
+		{"a.txt", "one\ntwo\n", "diff --git a/a.txt b/a.txt\nnew file mode 100644\n--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n"},
+		{"dir/b.txt", "only", "diff --git a/dir/b.txt b/dir/b.txt\nnew file mode 100644\n--- /dev/null\n+++ b/dir/b.txt\n@@ -0,0 +1 @@\n+only\n\\ No newline at end of file\n"},
+		{"empty", "", "diff --git a/empty b/empty\nnew file mode 100644\n"},
+		{"bin.dat", "a\x00b", "diff --git a/bin.dat b/bin.dat\nnew file mode 100644\nBinary files /dev/null and b/bin.dat differ\n"},
+		{"crlf.txt", "a\r\nb\r\n", "diff --git a/crlf.txt b/crlf.txt\nnew file mode 100644\n--- /dev/null\n+++ b/crlf.txt\n@@ -0,0 +1,2 @@\n+a\r\n+b\r\n"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(root, filepath.FromSlash(tt.file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := newFileDiff(root, tt.file); got != tt.want {
+			t.Errorf("newFileDiff(%q) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+
+	if got := newFileDiff(root, "missing.txt"); got != "" {
+		t.Errorf("newFileDiff(missing) = %q, want empty", got)
+	}
+}
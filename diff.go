@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultNewFileHeadLines = 50
+
+	// diffStatWidth 统计信息的宽度，避免长路径被缩写
+	diffStatWidth = 160
+	// maxDiffStatLines 提示词中统计信息的最大行数
+	maxDiffStatLines = 200
+)
+
+// fileDiff 单个文件的差异
+type fileDiff struct {
+	path    string
+	text    string
+	newFile bool
+}
+
+// preparedDiff 处理后用于生成提示词的差异
+type preparedDiff struct {
+	text       string
+	files      []fileDiff
+	newFiles   []string
+	condensed  []string
+	structural []string
+	skipped    []string
+	generated  []string
+	vendored   []vendoredDir
+	// restructure 以删除或移动文件为主时的分析结果
+	restructure restructureInfo
+}
+
+// splitDiff 将完整的差异按文件拆分
+func splitDiff(diff string) []fileDiff {
+	var files []fileDiff
+	var current []string
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		text := strings.Join(current, "\n")
+		files = append(files, fileDiff{
+			path:    diffPath(current),
+			text:    text,
+			newFile: strings.Contains(text, "\nnew file mode "),
+		})
+		current = nil
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return files
+}
+
+// diffPath 从文件差异的头部解析文件路径
+func diffPath(lines []string) string {
+	for _, line := range lines {
+		if strings.HasPrefix(line, "+++ b/") {
+			return strings.TrimPrefix(line, "+++ b/")
+		}
+		if strings.HasPrefix(line, "--- a/") {
+			return strings.TrimPrefix(line, "--- a/")
+		}
+		if strings.HasPrefix(line, "@@") {
+			break
+		}
+	}
+
+	// 二进制文件或仅权限变更时没有 ---/+++ 行，从 diff --git 行解析
+	header := strings.TrimPrefix(lines[0], "diff --git ")
+	if i := strings.Index(header, " b/"); i >= 0 {
+		return strings.Trim(header[i+3:], `"`)
+	}
+	return strings.TrimPrefix(strings.Fields(header)[0], "a/")
+}
+
+// joinDiffs 将文件差异重新合并为完整的差异
+func joinDiffs(files []fileDiff) string {
+	parts := make([]string, len(files))
+	for i, f := range files {
+		parts[i] = f.text
+	}
+	return strings.Join(parts, "\n") + "\n"
+}
+
+// normalizeNewlines 将 CRLF 换行统一为 LF
+//
+// Windows 中 core.autocrlf=false 的仓库，差异中的文件内容带有 CRLF，会使按行解析的前缀判断和路径解析出错；
+// 部分服务返回的提交信息也带有 CRLF，直接提交会在信息中留下 \r。
+func normalizeNewlines(text string) string {
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
+
+// prepareDiff 处理差异，使其适合放入提示词
+func prepareDiff(diff string) preparedDiff {
+	files := splitDiff(normalizeNewlines(diff))
+
+	var result preparedDiff
+	files, result.vendored = excludeVendoredFiles(files)
+	result.restructure = analyzeRestructure(files)
+	generated := generatedFiles(files)
+	for i, f := range files {
+		if isVendoredDir(f, result.vendored) {
+			continue
+		}
+		if description, ok := describeSpecialFile(f); ok {
+			files[i].text = description
+			continue
+		}
+		if attr, ok := generated[f.path]; ok {
+			files[i].text = excludeGeneratedFile(f, attr)
+			result.generated = append(result.generated, f.path)
+			continue
+		}
+		if result.restructure.kind == restructureRemoval && isDeletedFile(f) {
+			files[i].text = collapseDeletedFile(f)
+			continue
+		}
+		if text, ok := sanitizeFileDiff(f); ok {
+			f.text = text
+			files[i].text = text
+			result.skipped = append(result.skipped, f.path)
+		}
+		if config.StructuralDiff {
+			if text, ok := structuralGoDiff(f); ok {
+				files[i].text = text
+				result.structural = append(result.structural, f.path)
+			}
+		}
+		if f.newFile {
+			files[i].text = truncateNewFile(f.text, newFileHeadLines())
+			result.newFiles = append(result.newFiles, f.path)
+		}
+		if budget := maxFileDiffChars(); len(files[i].text) > budget {
+			files[i].text = condenseLargeFile(files[i], budget)
+			result.condensed = append(result.condensed, f.path)
+		}
+	}
+	result.text = joinDiffs(files)
+	result.files = files
+
+	return result
+}
+
+// newFileHeadLines 新文件在提示词中保留的行数
+func newFileHeadLines() int {
+	if config.NewFileHeadLines > 0 {
+		return config.NewFileHeadLines
+	}
+	return defaultNewFileHeadLines
+}
+
+// truncateNewFile 只保留新文件的前 n 行内容
+func truncateNewFile(text string, n int) string {
+	lines := strings.Split(text, "\n")
+
+	var kept []string
+	content := 0
+	for _, line := range lines {
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++ ") {
+			content++
+			if content > n {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+
+	if content > n {
+		kept = append(kept, fmt.Sprintf("... (%d more lines of this new file omitted)", content-n))
+	}
+
+	return strings.Join(kept, "\n")
+}
+
+// newFilesHint 提示模型描述新文件的用途
+func newFilesHint(newFiles []string) string {
+	if len(newFiles) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("These files are newly added: %s. Their content may be truncated to the first %d lines. Describe the purpose of the new files or modules instead of their contents line by line.", strings.Join(newFiles, ", "), newFileHeadLines())
+}
+
+// diffStatPreamble 返回放在差异之前的统计信息，超过 maxDiffStatLines 行时截断
+//
+// 差异被截断或分段摘要后，模型仍能从统计信息中看到所有改动的文件、增删行数以及新建、
+// 删除和重命名的文件。
+func diffStatPreamble() string {
+	stat := strings.TrimRight(normalizeNewlines(currentVCS.diffStat()), "\n")
+	if stat == "" {
+		return ""
+	}
+	lines := strings.Split(stat, "\n")
+	if len(lines) > maxDiffStatLines {
+		omitted := len(lines) - maxDiffStatLines
+		lines = append(lines[:maxDiffStatLines], fmt.Sprintf(" ... %d more lines omitted", omitted))
+	}
+	return strings.Join(lines, "\n")
+}
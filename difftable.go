@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// diffTableHeader 差异表格的表头，发布工具按此识别表格
+	diffTableHeader = "| File | + | - | Note |\n|------|---|---|------|"
+
+	// maxDiffTableRows 表格最多列出的文件数，其余的合并为一行
+	maxDiffTableRows = 50
+)
+
+// diffTableRow 表格中的一行，二进制文件的增删行数为 "-"
+type diffTableRow struct {
+	path    string
+	added   string
+	deleted string
+	note    string
+}
+
+// numstatRows 按 git diff --cached --numstat 统计每个文件的增删行数
+//
+// 使用 -z 输出，重命名的文件记为新路径，路径中的特殊字符也不会被转义。
+func numstatRows() []diffTableRow {
+	output := runGitCommand(withPathspecs("diff", "--cached", "--numstat", "-z")...)
+	fields := strings.Split(output, "\x00")
+
+	var rows []diffTableRow
+	for i := 0; i < len(fields); i++ {
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+		if path == "" && i+2 < len(fields) {
+			// 重命名时路径为空，随后是原路径和新路径
+			path = fields[i+2]
+			i += 2
+		}
+		rows = append(rows, diffTableRow{path: path, added: parts[0], deleted: parts[1]})
+	}
+	return rows
+}
+
+// diffStatRows 从差异统计每个文件的增删行数，用于 jj 和 Mercurial 仓库
+func diffStatRows(diff string) []diffTableRow {
+	var rows []diffTableRow
+	for _, f := range splitDiff(normalizeNewlines(diff)) {
+		_, hunks := splitHunks(f.text)
+		added, deleted := diffStat(hunks)
+		rows = append(rows, diffTableRow{path: f.path, added: fmt.Sprint(added), deleted: fmt.Sprint(deleted)})
+	}
+	return rows
+}
+
+// collapseVendoredRows 依赖目录中的文件合并为一行，与提示词中的处理一致
+func collapseVendoredRows(rows []diffTableRow, dirs []vendoredDir) []diffTableRow {
+	if len(dirs) == 0 {
+		return rows
+	}
+	var collapsed []diffTableRow
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		dir := ""
+		for _, d := range dirs {
+			if strings.HasPrefix(row.path, d.path) {
+				dir = d.path
+				break
+			}
+		}
+		if dir == "" {
+			collapsed = append(collapsed, row)
+			continue
+		}
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		for _, d := range dirs {
+			if d.path == dir {
+				collapsed = append(collapsed, diffTableRow{
+					path:    dir,
+					added:   fmt.Sprint(d.added),
+					deleted: fmt.Sprint(d.deleted),
+					note:    fmt.Sprintf("%d files, %s dependencies", d.files, d.ecosystem),
+				})
+			}
+		}
+	}
+	return collapsed
+}
+
+// fileNotes 请模型为每个文件写一句简短的说明，返回路径到说明的映射
+func fileNotes(ctx promptContext, rows []diffTableRow) (map[string]string, error) {
+	// --deadline 已到时不再请求，表格中不带说明
+	if !generationDeadline.IsZero() && time.Now().After(generationDeadline) {
+		return nil, errDeadlineExceeded
+	}
+	if offlineFallback() {
+		return nil, nil
+	}
+
+	var paths []string
+	for _, row := range rows {
+		if row.note == "" {
+			paths = append(paths, row.path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	content, err := requestCompletion(renderTemplate("file_notes", promptData{Diff: ctx.diff, Lang: ctx.lang, Files: paths}))
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*"))
+		for _, path := range paths {
+			rest, ok := strings.CutPrefix(strings.TrimPrefix(line, "`"), path)
+			if !ok {
+				continue
+			}
+			rest = strings.TrimPrefix(rest, "`")
+			if note, ok := strings.CutPrefix(rest, ":"); ok {
+				notes[path] = strings.TrimSpace(note)
+			}
+		}
+	}
+	return notes, nil
+}
+
+// escapeTableCell 转义表格单元格中的 | 和换行
+func escapeTableCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// buildDiffTable 生成每个文件的增删行数和说明的 markdown 表格
+func buildDiffTable(ctx promptContext) string {
+	var rows []diffTableRow
+	if currentVCS.name() == vcsGit {
+		rows = numstatRows()
+	} else {
+		rows = diffStatRows(currentVCS.diff())
+	}
+	rows = collapseVendoredRows(rows, ctx.vendored)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var omitted []diffTableRow
+	if len(rows) > maxDiffTableRows {
+		rows, omitted = rows[:maxDiffTableRows], rows[maxDiffTableRows:]
+	}
+
+	fmt.Fprintln(stdout, tr("table.generating"))
+	notes, err := fileNotes(ctx, rows)
+	if err != nil {
+		fmt.Fprintln(stderr, tr("table.notes_failed", err))
+	}
+
+	var b strings.Builder
+	b.WriteString(diffTableHeader)
+	for _, row := range rows {
+		note := row.note
+		if note == "" {
+			note = notes[row.path]
+		}
+		fmt.Fprintf(&b, "\n| %s | %s | %s | %s |", escapeTableCell(row.path), row.added, row.deleted, escapeTableCell(note))
+	}
+	if len(omitted) > 0 {
+		added, deleted := 0, 0
+		for _, row := range omitted {
+			var a, d int
+			fmt.Sscan(row.added, &a)
+			fmt.Sscan(row.deleted, &d)
+			added += a
+			deleted += d
+		}
+		fmt.Fprintf(&b, "\n| %d more files | %d | %d | |", len(omitted), added, deleted)
+	}
+	return b.String()
+}
+
+// appendDiffTable 开启 diff_table 时在正文末尾追加差异表格，放在 trailer 段落之前
+func appendDiffTable(message string, ctx promptContext) string {
+	if !config.DiffTable {
+		return message
+	}
+	table := buildDiffTable(ctx)
+	if table == "" {
+		return message
+	}
+
+	message = strings.TrimRight(message, "\n")
+	paragraphs := strings.Split(message, "\n\n")
+	if last := paragraphs[len(paragraphs)-1]; len(paragraphs) > 1 && isTrailerParagraph(last) {
+		body := strings.Join(paragraphs[:len(paragraphs)-1], "\n\n")
+		return body + "\n\n" + table + "\n\n" + last
+	}
+	return message + "\n\n" + table
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	formatSlack = "slack"
+
+	defaultDigestSince = "1.day.ago"
+	weekDigestSince    = "1.week.ago"
+)
+
+// digestCommit 摘要范围内的提交及其补丁
+type digestCommit struct {
+	hash    string
+	author  string
+	subject string
+	patch   string
+}
+
+func runDigest(args []string) {
+	since := defaultDigestSince
+	until := ""
+	author := ""
+	format := formatMarkdown
+	lang := ""
+	all := false
+	resume := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if arg == "--week" {
+			since = weekDigestSince
+		} else if arg == "--all" {
+			all = true
+		} else if arg == "--resume" {
+			resume = true
+		} else if value, ok := flagValue(args, &i, "--since"); ok {
+			since = value
+		} else if value, ok := flagValue(args, &i, "--until"); ok {
+			until = value
+		} else if value, ok := flagValue(args, &i, "--author"); ok {
+			author = value
+		} else if value, ok := flagValue(args, &i, "--format"); ok {
+			format = value
+		} else if value, ok := flagValue(args, &i, "--lang"); ok {
+			lang = value
+		} else {
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	if format != formatMarkdown && format != formatSlack {
+		fail("arg.invalid_choice", "--format", format, formatMarkdown+"|"+formatSlack)
+	}
+
+	ensureGitRepository()
+
+	if err := loadConfig(); err != nil {
+		fail("err.load_config", err)
+	}
+	if lang == "" {
+		lang = config.DefaultLang
+	}
+
+	logArgs := []string{"log", "-p", "--no-merges", "--no-color", "--since=" + since}
+	if until != "" {
+		logArgs = append(logArgs, "--until="+until)
+	}
+	if author != "" {
+		logArgs = append(logArgs, "--author="+author)
+	}
+	if all {
+		logArgs = append(logArgs, "--all")
+	}
+	commits := getDigestCommits(logArgs)
+	if len(commits) == 0 {
+		fmt.Fprintln(stdout, tr("digest.none", since))
+		os.Exit(0)
+	}
+
+	fmt.Fprintln(stderr, tr("digest.generating", len(commits), len(digestAuthors(commits)), since))
+
+	// 补丁过大时沿用分段摘要，先为每段提交生成摘要
+	changes := joinDigestPatches(commits)
+	var queue *jobQueue
+	if len(changes) > maxDiffChars() {
+		files := make([]fileDiff, len(commits))
+		for i, c := range commits {
+			files[i] = fileDiff{path: c.hash, text: c.patch}
+		}
+		queue = openJobQueue("digest", resume)
+		summary, err := summarizeCommits(files, buildDigestChunkPrompt, queue)
+		if err != nil {
+			failWith(err)
+		}
+		changes = summary
+	}
+
+	digest, err := requestCompletion(buildDigestPrompt(commits, changes, since, format, lang))
+	if err != nil {
+		failWith(err)
+	}
+	if digest == "" {
+		fail("digest.unable")
+	}
+	queue.finish()
+
+	fmt.Fprintln(stdout, digest)
+}
+
+// getDigestCommits 解析 git log -p 的输出，每个提交以 \x1e 开头
+func getDigestCommits(logArgs []string) []digestCommit {
+	output := normalizeNewlines(runGitCommand(append(logArgs, "--format=%x1e%h%x00%an%x00%s")...))
+
+	var commits []digestCommit
+	for _, record := range strings.Split(output, "\x1e") {
+		header, patch, _ := strings.Cut(record, "\n")
+		parts := strings.SplitN(header, "\x00", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		commits = append(commits, digestCommit{
+			hash:    parts[0],
+			author:  parts[1],
+			subject: parts[2],
+			patch:   fmt.Sprintf("commit %s by %s: %s\n%s", parts[0], parts[1], parts[2], strings.TrimSpace(patch)),
+		})
+	}
+	return commits
+}
+
+// digestAuthors 返回去重后的作者列表
+func digestAuthors(commits []digestCommit) []string {
+	var authors []string
+	for _, c := range commits {
+		if !containsString(authors, c.author) {
+			authors = append(authors, c.author)
+		}
+	}
+	return authors
+}
+
+// joinDigestPatches 连接所有提交的补丁
+func joinDigestPatches(commits []digestCommit) string {
+	patches := make([]string, len(commits))
+	for i, c := range commits {
+		patches[i] = c.patch
+	}
+	return strings.Join(patches, "\n\n")
+}
+
+// buildDigestChunkPrompt 构建一段提交补丁的摘要提示词
+func buildDigestChunkPrompt(chunk diffChunk) string {
+	return renderTemplate("digest_chunk", promptData{Diff: chunk.text})
+}
+
+// buildDigestPrompt 构建生成站会摘要的提示词
+func buildDigestPrompt(commits []digestCommit, changes, since, format, lang string) string {
+	return renderTemplate("digest", digestPromptData{
+		Commits: templateCommits(commits),
+		Authors: digestAuthors(commits),
+		Changes: changes,
+		Since:   since,
+		Format:  format,
+		Lang:    lang,
+	})
+}
+
+// templateCommits 转换为模板中使用的提交列表
+func templateCommits(commits []digestCommit) []templateCommit {
+	list := make([]templateCommit, len(commits))
+	for i, c := range commits {
+		list[i] = templateCommit{Hash: c.hash, Subject: c.subject, Author: c.author}
+	}
+	return list
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	checkOK   = "OK"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+// minGitMajor/minGitMinor 推荐的最低 git 版本
+const (
+	minGitMajor = 2
+	minGitMinor = 0
+)
+
+// checkResult 单项检查结果
+type checkResult struct {
+	name   string
+	status string
+	detail string
+	fix    string
+}
+
+// doctor 检查上下文
+type doctor struct {
+	results []checkResult
+}
+
+func (d *doctor) add(name, status, detail, fix string) {
+	d.results = append(d.results, checkResult{name: name, status: status, detail: detail, fix: fix})
+}
+
+func runDoctor(args []string) {
+	probe := false
+	for _, arg := range args {
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if arg == "--probe" {
+			probe = true
+			continue
+		}
+		failUsage("arg.unknown", arg)
+	}
+
+	d := &doctor{}
+	gitOK := d.checkGit()
+	if gitOK {
+		d.checkRepository()
+	}
+	if d.checkConfig() {
+		if offlineMode() {
+			d.checkOffline()
+		} else {
+			d.checkProxy()
+		}
+		if !offlineFallback() {
+			d.checkAPI()
+			d.checkCapabilities(probe)
+		}
+		d.checkSpellChecker()
+	}
+	if gitOK {
+		d.checkHooks()
+	}
+
+	failed := false
+	for _, r := range d.results {
+		if plainOutput {
+			fmt.Fprintf(stdout, "%s: %s: %s\n", r.name, r.status, r.detail)
+			if r.fix != "" {
+				fmt.Fprintln(stdout, tr("doctor.fix", r.fix))
+			}
+		} else {
+			fmt.Fprintf(stdout, "[%-4s] %s: %s\n", r.status, r.name, r.detail)
+			if r.fix != "" {
+				fmt.Fprintf(stdout, "       -> %s\n", r.fix)
+			}
+		}
+		if r.status == checkFail {
+			failed = true
+		}
+	}
+
+	fmt.Fprintln(stdout)
+	if failed {
+		fail("doctor.failed")
+	}
+	fmt.Fprintln(stdout, tr("doctor.passed"))
+}
+
+// checkGit 检查 git 是否可用以及版本
+func (d *doctor) checkGit() bool {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		d.add("git", checkFail, tr("doctor.git_not_found"), tr("doctor.git_not_found_fix"))
+		return false
+	}
+
+	output, err := gitOutput("--version")
+	if err != nil {
+		d.add("git", checkFail, tr("doctor.git_run_failed", gitPath, err), tr("doctor.git_run_failed_fix"))
+		return false
+	}
+
+	version := strings.TrimSpace(output)
+	major, minor, ok := parseGitVersion(version)
+	if !ok {
+		d.add("git", checkWarn, tr("doctor.git_version_unknown", version), "")
+		return true
+	}
+
+	if major < minGitMajor || (major == minGitMajor && minor < minGitMinor) {
+		d.add("git", checkWarn, tr("doctor.git_too_old", version, minGitMajor, minGitMinor), tr("doctor.git_too_old_fix"))
+		return true
+	}
+
+	if features := unsupportedGitFeatures(); len(features) > 0 {
+		d.add("git", checkOK, tr("doctor.git_compat", version, strings.Join(features, ", ")), "")
+		return true
+	}
+	d.add("git", checkOK, version, "")
+	return true
+}
+
+// checkRepository 检查当前目录是否在 git 仓库中
+func (d *doctor) checkRepository() {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		d.add("repository", checkFail, tr("doctor.not_repo"), tr("doctor.not_repo_fix"))
+		return
+	}
+
+	d.add("repository", checkOK, strings.TrimSpace(root), "")
+}
+
+// checkConfig 检查配置文件是否有效
+func (d *doctor) checkConfig() bool {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		d.add("config", checkFail, tr("doctor.no_home", err), tr("doctor.no_home_fix"))
+		return false
+	}
+
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		d.add("config", checkFail, tr("doctor.config_missing", configPath), tr("doctor.config_missing_fix"))
+		return false
+	}
+
+	if err := readConfig(configPath); err != nil {
+		d.add("config", checkFail, tr("doctor.config_invalid", configPath, err), tr("doctor.config_invalid_fix"))
+		return false
+	}
+
+	// 离线模式下服务商不在本机时不会发送请求，与 loadConfig 一样不需要密钥
+	if !offlineFallback() {
+		if config.APIKey == "" && config.AuthProvider != "" {
+			token, err := accessToken(config.AuthProvider)
+			if err != nil {
+				d.add("config", checkFail, err.Error(), "")
+				return false
+			}
+			config.APIKey = token
+		}
+		if config.APIKey == "" {
+			d.add("config", checkFail, tr("doctor.no_api_key"), tr("doctor.no_api_key_fix", configPath, strings.Join(apiKeyEnvCandidates(config.OpenAIEndpoint), ", ")))
+			return false
+		}
+	}
+
+	endpoint, err := url.Parse(config.OpenAIEndpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		d.add("config", checkFail, tr("doctor.bad_endpoint", config.OpenAIEndpoint), tr("doctor.bad_endpoint_fix"))
+		return false
+	}
+
+	for _, e := range configuredEndpoints() {
+		if err := checkEndpointAllowed(e); err != nil {
+			d.add("config", checkFail, err.Error(), "")
+			return false
+		}
+	}
+
+	d.add("config", checkOK, configPath, "")
+	return true
+}
+
+// checkProxy 检查代理是否可以连接
+func (d *doctor) checkProxy() {
+	var proxyURL *url.URL
+	source := "proxy_url"
+	if p, _ := selectProfile(); p != nil && p.ProxyURL != "" {
+		source = "profiles." + selectedProfileName() + ".proxy_url"
+	}
+
+	if config.ProxyURL == proxyDirect {
+		d.add("proxy", checkOK, tr("doctor.proxy_direct", source), "")
+		return
+	} else if config.ProxyURL != "" {
+		u, err := parseProxyURL(config.ProxyURL, config.ProxyUsername, config.ProxyPassword)
+		if err != nil {
+			d.add("proxy", checkFail, err.Error(), tr("doctor.proxy_invalid_fix"))
+			return
+		}
+		proxyURL = u
+	} else if proxyFromEnv() {
+		req, err := http.NewRequest("POST", config.OpenAIEndpoint, nil)
+		if err == nil {
+			proxyURL, _ = http.ProxyFromEnvironment(req)
+		}
+		source = "environment"
+	}
+
+	if proxyURL == nil {
+		d.add("proxy", checkOK, tr("doctor.no_proxy"), "")
+		return
+	}
+
+	address := proxyURL.Host
+	if proxyURL.Port() == "" {
+		switch proxyURL.Scheme {
+		case "https":
+			address = net.JoinHostPort(proxyURL.Hostname(), "443")
+		case "socks5", "socks5h":
+			address = net.JoinHostPort(proxyURL.Hostname(), "1080")
+		default:
+			address = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		d.add("proxy", checkFail, tr("doctor.proxy_unreachable", address, source, err), tr("doctor.proxy_unreachable_fix"))
+		return
+	}
+	conn.Close()
+
+	d.add("proxy", checkOK, tr("doctor.proxy_ok", address, source), "")
+}
+
+// checkOffline 离线模式下说明如何生成提交信息，不检查代理
+func (d *doctor) checkOffline() {
+	if localProvider() {
+		d.add("offline", checkOK, tr("doctor.offline_local", strings.Join(configuredEndpoints(), ", ")), "")
+		return
+	}
+	d.add("offline", checkWarn, tr("doctor.offline_heuristic", strings.Join(configuredEndpoints(), ", ")), tr("doctor.offline_heuristic_fix"))
+}
+
+// modelsEndpoint 根据聊天接口地址推导模型列表接口地址
+func modelsEndpoint(endpoint string) string {
+	if strings.HasSuffix(endpoint, "/chat/completions") {
+		return strings.TrimSuffix(endpoint, "/chat/completions") + "/models"
+	}
+	return ""
+}
+
+// checkAPI 通过模型列表接口检查 API 连通性和模型是否可用
+func (d *doctor) checkAPI() {
+	endpoint := modelsEndpoint(config.OpenAIEndpoint)
+	if endpoint == "" {
+		d.add("api", checkWarn, tr("doctor.no_models_endpoint", config.OpenAIEndpoint), "")
+		return
+	}
+
+	client, err := newHTTPClient(10 * time.Second)
+	if err != nil {
+		d.add("api", checkFail, err.Error(), tr("doctor.proxy_settings_fix"))
+		return
+	}
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		d.add("api", checkFail, err.Error(), "")
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+config.APIKey)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		d.add("api", checkFail, tr("doctor.api_unreachable", endpoint, err), tr("doctor.api_unreachable_fix"))
+		return
+	}
+	defer resp.Body.Close()
+	latency := time.Since(start).Round(time.Millisecond)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		d.add("api", checkFail, tr("doctor.api_key_rejected", endpoint, resp.StatusCode), tr("doctor.api_key_rejected_fix"))
+		return
+	case resp.StatusCode == http.StatusNotFound:
+		d.add("api", checkWarn, tr("doctor.models_unsupported", endpoint), "")
+		d.add("model", checkWarn, tr("doctor.model_unverified", config.Model), "")
+		return
+	case resp.StatusCode >= 400:
+		d.add("api", checkFail, tr("doctor.api_http_error", endpoint, resp.StatusCode), tr("doctor.api_http_error_fix"))
+		return
+	}
+	d.add("api", checkOK, tr("doctor.api_ok", endpoint, latency), "")
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		d.add("model", checkWarn, tr("doctor.models_read_failed", err), "")
+		return
+	}
+
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &models); err != nil || len(models.Data) == 0 {
+		d.add("model", checkWarn, tr("doctor.model_unverified", config.Model), "")
+		return
+	}
+
+	for _, m := range models.Data {
+		if m.ID == config.Model {
+			d.add("model", checkOK, config.Model, "")
+			return
+		}
+	}
+	d.add("model", checkFail, tr("doctor.model_unavailable", config.Model), tr("doctor.model_unavailable_fix"))
+}
+
+// checkSpellChecker 开启 spell_check 时检查拼写检查程序和词典是否可用
+func (d *doctor) checkSpellChecker() {
+	if config.SpellCheck == "" {
+		return
+	}
+
+	checker, err := findSpellChecker()
+	if err != nil {
+		d.add("spell", checkWarn, tr("doctor.spell_missing"), tr("doctor.spell_missing_fix"))
+		return
+	}
+	dicts := spellDictionaries(checker, config.DefaultLang)
+	if len(dicts) == 0 {
+		d.add("spell", checkWarn, tr("doctor.spell_no_dictionary", config.DefaultLang), tr("doctor.spell_no_dictionary_fix"))
+		return
+	}
+	for _, dict := range dicts {
+		if _, err := checkSpelling(checker, dict, []string{"commit"}); err != nil {
+			d.add("spell", checkWarn, tr("spell.failed", checker, dict, err), tr("doctor.spell_no_dictionary_fix"))
+			return
+		}
+	}
+	d.add("spell", checkOK, fmt.Sprintf("%s (%s)", checker, strings.Join(dicts, ", ")), "")
+}
+
+// checkCapabilities 显示服务商支持的请求参数，probe 为 true 时重新检测
+func (d *doctor) checkCapabilities(probe bool) {
+	if config.Capabilities != nil {
+		d.add("capabilities", checkOK, tr("doctor.caps_configured", config.Capabilities.describe()), "")
+		return
+	}
+
+	key := capabilitiesKey(config.Model)
+	if probe {
+		c, err := probeCapabilities(config.Model)
+		if err != nil {
+			d.add("capabilities", checkWarn, tr("doctor.caps_failed", err), "")
+			return
+		}
+		saveProbedCapabilities(key, c)
+	}
+
+	p, ok := loadProbedCapabilities()[key]
+	if !ok || p.Version != capabilitiesVersion || p.Failed {
+		d.add("capabilities", checkOK, tr("doctor.caps_unknown", config.Model), tr("doctor.caps_unknown_fix"))
+		return
+	}
+	d.add("capabilities", checkOK, tr("doctor.caps_probed", p.Result.describe(), time.Unix(p.ProbedAt, 0).Format("2006-01-02 15:04")), "")
+}
+
+// checkHooks 检查会影响提交和推送流程的 git 钩子
+func (d *doctor) checkHooks() {
+	hooksDir, err := gitPath("hooks")
+	if err != nil {
+		return
+	}
+
+	var installed []string
+	for _, name := range []string{"pre-commit", "prepare-commit-msg", "commit-msg", pushHookName} {
+		content, err := os.ReadFile(filepath.Join(hooksDir, name))
+		if err != nil {
+			continue
+		}
+		if strings.Contains(string(content), "aicommit") {
+			installed = append(installed, name+" (aicommit)")
+		} else {
+			installed = append(installed, name)
+		}
+	}
+
+	if len(installed) == 0 {
+		d.add("hooks", checkOK, tr("doctor.no_hooks"), "")
+		return
+	}
+	d.add("hooks", checkWarn, tr("doctor.hooks_installed", strings.Join(installed, ", ")), tr("doctor.hooks_installed_fix", hooksDir))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	editsFileName = "edits.json"
+
+	// maxRecordedEdits 每个仓库最多保存的修改记录数
+	maxRecordedEdits = 20
+
+	defaultEditExamples = 3
+)
+
+// editExample 一条生成后被用户修改过的提交信息
+type editExample struct {
+	Generated string `json:"generated"`
+	Edited    string `json:"edited"`
+}
+
+// editExamples 放入提示词的修改记录数，负数表示不记录也不使用
+func editExamples() int {
+	if config.EditExamples == 0 {
+		return defaultEditExamples
+	}
+	return config.EditExamples
+}
+
+// loadEdits 读取当前仓库的修改记录，从旧到新排列
+func loadEdits() []editExample {
+	path, err := repoStatePath(editsFileName)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var edits []editExample
+	json.Unmarshal(data, &edits)
+	return edits
+}
+
+// recordEdit 用户在编辑器中修改了生成的信息时保存修改前后的内容，只保留最近的记录
+func recordEdit(generated, edited string) {
+	generated, edited = strings.TrimSpace(generated), strings.TrimSpace(edited)
+	if editExamples() < 0 || generated == "" || edited == "" || generated == edited {
+		return
+	}
+
+	edits := append(loadEdits(), editExample{Generated: generated, Edited: edited})
+	if len(edits) > maxRecordedEdits {
+		edits = edits[len(edits)-maxRecordedEdits:]
+	}
+
+	path, err := repoStatePath(editsFileName)
+	if err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(edits, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err == nil {
+		fmt.Fprintln(stdout, tr("edits.recorded"))
+	}
+}
+
+// recentEdits 返回放入提示词的最近几条修改记录
+func recentEdits() []editExample {
+	n := editExamples()
+	if n < 0 {
+		return nil
+	}
+	edits := loadEdits()
+	if len(edits) > n {
+		edits = edits[len(edits)-n:]
+	}
+	return edits
+}
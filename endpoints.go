@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	endpointRoundRobin   = "round_robin"
+	endpointLeastLatency = "least_latency"
+
+	// endpointStateFileName 各端点的健康状态，保存在配置目录中供之后的运行使用
+	endpointStateFileName = "endpoints.json"
+
+	// 端点失败后暂停使用的时间，连续失败时加倍
+	endpointCooldown    = 30 * time.Second
+	maxEndpointCooldown = 5 * time.Minute
+
+	// latencyWeight 计算平均延迟时最近一次请求的权重
+	latencyWeight = 0.3
+)
+
+// endpointHealth 一个端点的健康状态
+type endpointHealth struct {
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+	Failures  int     `json:"failures,omitempty"`
+	DownUntil int64   `json:"down_until,omitempty"`
+}
+
+// endpointState 所有端点的健康状态和轮询位置
+type endpointState struct {
+	Next      int                        `json:"next"`
+	Endpoints map[string]*endpointHealth `json:"endpoints"`
+}
+
+var (
+	endpointMu    sync.Mutex
+	endpointCache *endpointState
+)
+
+// configuredEndpoints 返回可用的端点，配置了 openai_endpoints 时使用该列表
+func configuredEndpoints() []string {
+	if len(config.OpenAIEndpoints) > 0 {
+		return config.OpenAIEndpoints
+	}
+	return []string{config.OpenAIEndpoint}
+}
+
+// endpointStrategy 返回选择端点的方式
+func endpointStrategy() string {
+	if config.EndpointStrategy == "" {
+		return endpointRoundRobin
+	}
+	return config.EndpointStrategy
+}
+
+// endpointStatePath 返回端点健康状态文件的路径
+func endpointStatePath() (string, error) {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), endpointStateFileName), nil
+}
+
+// loadEndpointState 读取端点健康状态，调用方需持有 endpointMu
+//
+// 状态文件在同一台机器的多次运行之间共享，文件损坏或不存在时从空状态开始。
+func loadEndpointState() *endpointState {
+	state := &endpointState{Endpoints: map[string]*endpointHealth{}}
+	if path, err := endpointStatePath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, state)
+		}
+	}
+	if state.Endpoints == nil {
+		state.Endpoints = map[string]*endpointHealth{}
+	}
+	return state
+}
+
+// saveEndpointState 保存端点健康状态，先写入临时文件再改名，避免并发运行读到写了一半的文件
+func saveEndpointState(state *endpointState) {
+	path, err := endpointStatePath()
+	if err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// endpointHealthFor 返回端点的健康状态，调用方需持有 endpointMu
+func endpointHealthFor(endpoint string) *endpointHealth {
+	if endpointCache == nil {
+		endpointCache = loadEndpointState()
+	}
+	health, ok := endpointCache.Endpoints[endpoint]
+	if !ok {
+		health = &endpointHealth{}
+		endpointCache.Endpoints[endpoint] = health
+	}
+	return health
+}
+
+// selectEndpoint 为一次请求选择端点，跳过本次请求已经失败的端点和暂停使用中的端点
+//
+// 所有端点都不可用时仍从未尝试过的端点中选择，全部都已尝试过时返回空。
+func selectEndpoint(tried map[string]bool) string {
+	endpoints := configuredEndpoints()
+	if len(endpoints) == 1 {
+		if tried[endpoints[0]] {
+			return ""
+		}
+		return endpoints[0]
+	}
+
+	endpointMu.Lock()
+	defer endpointMu.Unlock()
+
+	now := time.Now().Unix()
+	var candidates, down []string
+	for _, e := range endpoints {
+		if tried[e] {
+			continue
+		}
+		if endpointHealthFor(e).DownUntil > now {
+			down = append(down, e)
+		} else {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = down
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	if endpointStrategy() == endpointLeastLatency {
+		// 还没有延迟数据的端点排在最前面，先各请求一次
+		best := candidates[0]
+		for _, e := range candidates[1:] {
+			if endpointHealthFor(e).LatencyMS < endpointHealthFor(best).LatencyMS {
+				best = e
+			}
+		}
+		return best
+	}
+
+	selected := candidates[endpointCache.Next%len(candidates)]
+	endpointCache.Next = (endpointCache.Next + 1) % len(endpoints)
+	saveEndpointState(endpointCache)
+	return selected
+}
+
+// recordEndpointSuccess 记录端点请求成功及其延迟
+func recordEndpointSuccess(endpoint string, latency time.Duration) {
+	if len(configuredEndpoints()) == 1 {
+		return
+	}
+
+	endpointMu.Lock()
+	defer endpointMu.Unlock()
+
+	health := endpointHealthFor(endpoint)
+	ms := float64(latency) / float64(time.Millisecond)
+	if health.LatencyMS == 0 {
+		health.LatencyMS = ms
+	} else {
+		health.LatencyMS = latencyWeight*ms + (1-latencyWeight)*health.LatencyMS
+	}
+	health.Failures = 0
+	health.DownUntil = 0
+	saveEndpointState(endpointCache)
+}
+
+// recordEndpointFailure 记录端点请求失败，暂停使用该端点一段时间
+func recordEndpointFailure(endpoint string) {
+	if len(configuredEndpoints()) == 1 {
+		return
+	}
+
+	endpointMu.Lock()
+	defer endpointMu.Unlock()
+
+	health := endpointHealthFor(endpoint)
+	health.Failures++
+	cooldown := endpointCooldown << (health.Failures - 1)
+	if cooldown > maxEndpointCooldown || cooldown <= 0 {
+		cooldown = maxEndpointCooldown
+	}
+	health.DownUntil = time.Now().Add(cooldown).Unix()
+	saveEndpointState(endpointCache)
+}
+
+// validateEndpoints 校验 openai_endpoints 和 endpoint_strategy，用户配置和服务商配置共用
+func validateEndpoints(prefix string, endpoints []string, strategy string, lines map[string]int) []configIssue {
+	var issues []configIssue
+	for i, endpoint := range endpoints {
+		if !isHTTPURL(endpoint) {
+			issues = append(issues, configIssue{line: lines[prefix+"openai_endpoints"], key: fmt.Sprintf("%sopenai_endpoints[%d]", prefix, i), message: tr("schema.invalid_url")})
+		}
+	}
+	switch strategy {
+	case "", endpointRoundRobin, endpointLeastLatency:
+	default:
+		key := prefix + "endpoint_strategy"
+		issues = append(issues, configIssue{line: lines[key], key: key, message: tr("schema.invalid_choice", endpointRoundRobin+", "+endpointLeastLatency)})
+	}
+	return issues
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// errorFormatEnv 未通过 --error-format 指定时读取的环境变量
+	errorFormatEnv = "AICOMMIT_ERROR_FORMAT"
+
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+
+	// unknownErrorCode 没有错误码的错误使用的代码
+	unknownErrorCode = "err.unknown"
+)
+
+// errorFormat 错误输出格式，json 时在 stderr 输出一行结构化的错误
+var errorFormat = errorFormatText
+
+// currentStage 当前所处的阶段，随结构化错误一起输出
+var currentStage = "args"
+
+// codedError 带错误码的错误，错误码即对应的翻译键，不随界面语言变化
+type codedError struct {
+	code      string
+	message   string
+	retryable bool
+}
+
+func (e *codedError) Error() string {
+	return e.message
+}
+
+// errorReport 结构化错误输出的内容
+type errorReport struct {
+	Code      string `json:"code"`
+	Stage     string `json:"stage"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+}
+
+// newError 创建带错误码的错误
+//
+// 参数中包含可重试的错误时，新错误同样可重试，例如分段总结失败包装了网络错误。
+func newError(key string, args ...interface{}) error {
+	retryable := false
+	for _, arg := range args {
+		if err, ok := arg.(error); ok && isRetryable(err) {
+			retryable = true
+		}
+	}
+	return &codedError{code: key, message: tr(key, args...), retryable: retryable}
+}
+
+// newRetryableError 创建可重试的错误，如网络错误、限流和服务端错误
+func newRetryableError(key string, args ...interface{}) error {
+	return &codedError{code: key, message: tr(key, args...), retryable: true}
+}
+
+// statusError 创建 HTTP 请求失败的错误，限流和服务端错误可重试
+func statusError(status int, key string, args ...interface{}) error {
+	if isRetryableStatus(status) {
+		return newRetryableError(key, args...)
+	}
+	return newError(key, args...)
+}
+
+// isRetryable 判断错误是否值得稍后重试
+func isRetryable(err error) bool {
+	var coded *codedError
+	return errors.As(err, &coded) && coded.retryable
+}
+
+// setErrorFormat 设置错误输出格式
+func setErrorFormat(format string) {
+	switch format {
+	case errorFormatText, errorFormatJSON:
+		errorFormat = format
+		os.Setenv(errorFormatEnv, format)
+	default:
+		fail("arg.bad_error_format", format)
+	}
+}
+
+// initErrorFormat 读取环境变量中的错误输出格式，无效的值忽略
+func initErrorFormat() {
+	if format := strings.TrimSpace(os.Getenv(errorFormatEnv)); format == errorFormatJSON {
+		errorFormat = errorFormatJSON
+	}
+}
+
+// setStage 记录当前阶段
+func setStage(stage string) {
+	currentStage = stage
+}
+
+// reportError 按错误输出格式输出错误
+func reportError(err error) {
+	if errorFormat != errorFormatJSON {
+		fmt.Fprintln(stdout, err)
+		return
+	}
+
+	report := errorReport{Code: unknownErrorCode, Stage: currentStage, Message: err.Error()}
+	var coded *codedError
+	if errors.As(err, &coded) {
+		report.Code = coded.code
+		report.Retryable = coded.retryable
+	}
+	data, _ := json.Marshal(report)
+	fmt.Fprintln(stderr, string(data))
+}
+
+// fail 输出错误并退出
+func fail(key string, args ...interface{}) {
+	failWith(newError(key, args...))
+}
+
+var (
+	failureMu   sync.Mutex
+	heldFailure chan error
+)
+
+// holdFailures 之后 failWith 不再退出进程，错误交给 releaseFailures 的调用方
+//
+// 用于把终端交给分页器等子进程期间：后台任务出错时若直接退出，子进程仍在运行，
+// 终端会处于异常状态。期间只能有后台 goroutine 调用 failWith。
+func holdFailures() {
+	failureMu.Lock()
+	heldFailure = make(chan error, 1)
+	failureMu.Unlock()
+}
+
+// releaseFailures 恢复 failWith 的正常行为，返回期间发生的第一个错误
+func releaseFailures() error {
+	failureMu.Lock()
+	ch := heldFailure
+	heldFailure = nil
+	failureMu.Unlock()
+
+	select {
+	case err := <-ch:
+		return err
+	default:
+		return nil
+	}
+}
+
+// failWith 输出已有的错误并退出
+func failWith(err error) {
+	failureMu.Lock()
+	ch := heldFailure
+	failureMu.Unlock()
+	if ch != nil {
+		select {
+		case ch <- err:
+		default:
+		}
+		// 由主流程在子进程结束后输出错误并退出，出错的 goroutine 不再继续
+		select {}
+	}
+
+	reportError(err)
+	os.Exit(1)
+}
+
+// failUsage 参数错误时输出错误和帮助信息并退出，json 格式下不输出帮助信息
+func failUsage(key string, args ...interface{}) {
+	if errorFormat == errorFormatJSON {
+		fail(key, args...)
+	}
+	fmt.Fprintln(stdout, tr(key, args...))
+	printHelp()
+	os.Exit(1)
+}
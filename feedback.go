@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	feedbackFileName = "feedback.json"
+
+	feedbackGood = "good"
+	feedbackBad  = "bad"
+
+	// maxRecordedFeedback 每个仓库最多保存的评价数
+	maxRecordedFeedback = 200
+
+	// statsRecentDays aicommit stats 中“最近”的天数
+	statsRecentDays = 30
+
+	// statsTopReasons aicommit stats 列出的最常见原因数
+	statsTopReasons = 5
+)
+
+// feedbackEntry 对一个提交信息的评价，只保存在本地，不包含作者等信息
+type feedbackEntry struct {
+	Commit  string `json:"commit"`
+	Message string `json:"message"`
+	Rating  string `json:"rating"`
+	Reason  string `json:"reason,omitempty"`
+	Time    string `json:"time"`
+}
+
+func runFeedback(args []string) {
+	rating, rev, reason := "", "", ""
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if value, ok := flagValue(args, &i, "--reason"); ok {
+			reason = strings.TrimSpace(value)
+		} else if strings.HasPrefix(arg, "-") {
+			failUsage("arg.unknown", arg)
+		} else if rating == "" {
+			rating = arg
+		} else if rev == "" {
+			rev = arg
+		} else {
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	if rating != feedbackGood && rating != feedbackBad {
+		fail("arg.invalid_choice", "feedback", rating, feedbackGood+"|"+feedbackBad)
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	ensureGitRepository()
+	readOptionalConfig()
+
+	output := normalizeNewlines(runGitCommand("log", "-1", "--format=%H%x00%B", rev))
+	hash, message, _ := strings.Cut(strings.TrimSpace(output), "\x00")
+	message = strings.TrimSpace(message)
+
+	// 同一个提交再次评价时替换之前的评价
+	var entries []feedbackEntry
+	for _, e := range loadFeedback() {
+		if e.Commit != hash {
+			entries = append(entries, e)
+		}
+	}
+	entries = append(entries, feedbackEntry{
+		Commit:  hash,
+		Message: message,
+		Rating:  rating,
+		Reason:  reason,
+		Time:    time.Now().Format(time.RFC3339),
+	})
+	if len(entries) > maxRecordedFeedback {
+		entries = entries[len(entries)-maxRecordedFeedback:]
+	}
+
+	if err := saveFeedback(entries); err != nil {
+		fail("feedback.save_failed", err)
+	}
+	fmt.Fprintln(stdout, tr("feedback.recorded", shortHash(hash), messageSubject(message)))
+	if rating == feedbackBad && feedbackExamples() == 0 {
+		fmt.Fprintln(stdout, tr("feedback.examples_off"))
+	}
+}
+
+func runStats(args []string) {
+	for _, arg := range args {
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		}
+		failUsage("arg.unknown", arg)
+	}
+
+	ensureGitRepository()
+
+	entries := loadFeedback()
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout, tr("stats.none"))
+		return
+	}
+
+	since := time.Now().AddDate(0, 0, -statsRecentDays)
+	var good, bad, recentGood, recentBad int
+	reasons := make(map[string]int)
+	for _, e := range entries {
+		recent := false
+		if t, err := time.Parse(time.RFC3339, e.Time); err == nil && t.After(since) {
+			recent = true
+		}
+		if e.Rating == feedbackGood {
+			good++
+			if recent {
+				recentGood++
+			}
+			continue
+		}
+		bad++
+		if recent {
+			recentBad++
+		}
+		if e.Reason != "" {
+			reasons[e.Reason]++
+		}
+	}
+
+	fmt.Fprintln(stdout, tr("stats.total", len(entries), good, bad, percent(good, len(entries))))
+	if n := recentGood + recentBad; n > 0 && n < len(entries) {
+		fmt.Fprintln(stdout, tr("stats.recent", statsRecentDays, n, recentGood, recentBad, percent(recentGood, n)))
+	}
+
+	if len(reasons) > 0 {
+		keys := make([]string, 0, len(reasons))
+		for r := range reasons {
+			keys = append(keys, r)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if reasons[keys[i]] != reasons[keys[j]] {
+				return reasons[keys[i]] > reasons[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		if len(keys) > statsTopReasons {
+			keys = keys[:statsTopReasons]
+		}
+		fmt.Fprintln(stdout, tr("stats.reasons"))
+		for _, r := range keys {
+			fmt.Fprintf(stdout, "  %3d  %s\n", reasons[r], r)
+		}
+	}
+}
+
+// percent 返回 n 占 total 的百分比
+func percent(n, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return n * 100 / total
+}
+
+// shortHash 返回提交哈希的前 7 位
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// readOptionalConfig 配置文件存在时读取，用于不需要 API 密钥的子命令
+func readOptionalConfig() {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		return
+	}
+	if err := readConfig(configPath); err != nil {
+		fail("err.load_config", err)
+	}
+}
+
+// feedbackExamples 放入提示词的差评数，默认为 0，即不使用
+func feedbackExamples() int {
+	if config.FeedbackExamples < 0 {
+		return 0
+	}
+	return config.FeedbackExamples
+}
+
+// loadFeedback 读取当前仓库的评价，从旧到新排列
+func loadFeedback() []feedbackEntry {
+	path, err := repoStatePath(feedbackFileName)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entries []feedbackEntry
+	json.Unmarshal(data, &entries)
+	return entries
+}
+
+// saveFeedback 保存当前仓库的评价
+func saveFeedback(entries []feedbackEntry) error {
+	path, err := repoStatePath(feedbackFileName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// rejectedExamples 返回放入提示词的最近几条差评
+func rejectedExamples() []feedbackEntry {
+	n := feedbackExamples()
+	if n == 0 {
+		return nil
+	}
+	var rejected []feedbackEntry
+	for _, e := range loadFeedback() {
+		if e.Rating == feedbackBad && e.Message != "" {
+			rejected = append(rejected, e)
+		}
+	}
+	if len(rejected) > n {
+		rejected = rejected[len(rejected)-n:]
+	}
+	return rejected
+}
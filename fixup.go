@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func runFixup(args []string) {
+	squash := false
+	allowSensitive := false
+	target := ""
+	var paths []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--help" || arg == "-h":
+			printHelp()
+			os.Exit(0)
+		case arg == "--squash":
+			squash = true
+		case arg == "--allow-sensitive":
+			allowSensitive = true
+		case arg == "--":
+			paths = append(paths, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "-"):
+			failUsage("arg.unknown", arg)
+		case target == "":
+			target = arg
+		default:
+			paths = append(paths, arg)
+		}
+	}
+
+	if target == "" {
+		fail("fixup.no_target")
+	}
+
+	ensureGitRepository()
+
+	if err := loadConfig(); err != nil {
+		fail("err.load_config", err)
+	}
+
+	// 解析目标提交
+	output, err := gitOutput("log", "-1", "--format=%h%x00%s", target)
+	parts := strings.SplitN(strings.TrimSpace(output), "\x00", 2)
+	if err != nil || len(parts) != 2 {
+		fail("fixup.bad_target", target)
+	}
+	targetHash, targetSubject := parts[0], parts[1]
+
+	if err := loadRepoConfig(); err != nil {
+		fail("err.load_repo_config", err)
+	}
+
+	// 暂存指定的路径，未指定且暂存区为空时暂存所有更改
+	stage := len(paths) > 0 || strings.TrimSpace(runGitCommand("diff", "--cached", "--name-only")) == ""
+	checkSensitiveFiles(pendingFiles(paths, stage), allowSensitive)
+	if len(paths) > 0 {
+		runGitCommand(append([]string{"add", "--"}, paths...)...)
+	} else if stage {
+		stageAll()
+	}
+
+	diff := runGitCommand("diff", "--cached")
+	if diff == "" {
+		fmt.Fprintln(stdout, tr("commit.no_diff"))
+		os.Exit(0)
+	}
+
+	prepared := prepareDiff(diff)
+	body, err := requestCompletion(buildFixupPrompt(prepared.text, targetHash, targetSubject, squash))
+	if err != nil {
+		failWith(err)
+	}
+
+	prefix := "fixup! "
+	if squash {
+		prefix = "squash! "
+	}
+	message := prefix + targetSubject
+	if body = strings.TrimSpace(body); body != "" {
+		message += "\n\n" + body
+	}
+
+	warnStrippedLines(message, false)
+	commitChanges(message)
+
+	fmt.Fprintln(stdout, tr("commit.complete"))
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, committedMessage())
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, tr("fixup.rebase_hint", targetHash))
+}
+
+// buildFixupPrompt 构建生成 fixup/squash 提交正文的提示词
+func buildFixupPrompt(diff, targetHash, targetSubject string, squash bool) string {
+	kind := "fixup"
+	if squash {
+		kind = "squash"
+	}
+
+	return renderTemplate("fixup", fixupPromptData{
+		Diff:          diff,
+		Lang:          config.DefaultLang,
+		Kind:          kind,
+		Target:        targetHash,
+		TargetSubject: targetSubject,
+	})
+}
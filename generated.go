@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// generatedAttributes 标记生成文件的 .gitattributes 属性，与 GitHub 折叠生成文件的规则相同
+var generatedAttributes = []string{"linguist-generated", "diff"}
+
+// generatedFiles 返回 .gitattributes 中标记为 linguist-generated 或 -diff 的文件及其属性
+//
+// 只支持 git 仓库。差异中的路径相对于仓库根目录，因此在根目录中运行 git check-attr；
+// 路径通过标准输入传入，避免文件很多时超出命令行长度的限制。
+func generatedFiles(files []fileDiff) map[string]string {
+	if config.IncludeGenerated || currentVCS.name() != vcsGit || len(files) == 0 {
+		return nil
+	}
+	root, err := currentVCS.root()
+	if err != nil {
+		return nil
+	}
+
+	var input strings.Builder
+	for _, f := range files {
+		input.WriteString(f.path)
+		input.WriteByte(0)
+	}
+	cmd := exec.Command("git", gitCompatArgs(append([]string{"check-attr", "-z", "--stdin"}, generatedAttributes...))...)
+	cmd.Dir = root
+	cmd.Stdin = strings.NewReader(input.String())
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	// -z 的输出为 "<路径>\0<属性>\0<值>\0" 的重复
+	generated := make(map[string]string)
+	fields := strings.Split(string(output), "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		path, attr, value := fields[i], fields[i+1], fields[i+2]
+		switch {
+		case attr == "linguist-generated" && (value == "set" || value == "true"):
+			generated[path] = attr
+		case attr == "diff" && value == "unset" && generated[path] == "":
+			generated[path] = "-diff"
+		}
+	}
+	return generated
+}
+
+// excludeGeneratedFile 将生成文件的差异替换为统计信息
+func excludeGeneratedFile(f fileDiff, attr string) string {
+	header, hunks := splitHunks(f.text)
+	if len(hunks) == 0 {
+		// -diff 的文件 git 只输出 "Binary files ... differ"，没有行数
+		return fmt.Sprintf("%s\n%s | content excluded (marked %s in .gitattributes)", header, f.path, attr)
+	}
+	added, deleted := diffStat(hunks)
+	return fmt.Sprintf("%s\n%s | %d insertions(+), %d deletions(-), content excluded (marked %s in .gitattributes)", header, f.path, added, deleted, attr)
+}
+
+// generatedFilesHint 告知模型哪些文件是生成的，内容没有放入提示词
+func generatedFilesHint(generated []string) string {
+	if len(generated) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("These files are marked as generated in .gitattributes, so their content was excluded: %s. Mention them only briefly (for example as regenerated output) and describe the hand-written changes that caused them.", strings.Join(generated, ", "))
+}
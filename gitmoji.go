@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// defaultEmojiMap 默认的提交类型到 gitmoji 的映射
+var defaultEmojiMap = map[string]string{
+	"feat":     "✨",
+	"fix":      "🐛",
+	"docs":     "📝",
+	"style":    "🎨",
+	"refactor": "♻️",
+	"perf":     "⚡️",
+	"test":     "✅",
+	"build":    "📦️",
+	"ci":       "👷",
+	"chore":    "🔧",
+	"revert":   "⏪️",
+	"security": "🔒️",
+}
+
+// conventionalTypePattern 提取 Conventional Commits 标题中的类型
+var conventionalTypePattern = regexp.MustCompile(`^([a-z]+)(?:\([^)]+\))?!?: `)
+
+// shortcodePattern 匹配 :sparkles: 形式的 emoji 短代码
+var shortcodePattern = regexp.MustCompile(`^:[a-z0-9_+-]+:$`)
+
+// emojiMap 返回生效的类型到 emoji 的映射
+//
+// 配置了 emoji_map 时完全替换默认映射；使用默认映射时去掉被禁用的 emoji。
+func emojiMap() map[string]string {
+	if len(config.EmojiMap) > 0 {
+		return config.EmojiMap
+	}
+
+	mapping := make(map[string]string, len(defaultEmojiMap))
+	for t, emoji := range defaultEmojiMap {
+		if !containsEmoji(config.DisallowedEmojis, emoji) {
+			mapping[t] = emoji
+		}
+	}
+	return mapping
+}
+
+// normalizeEmoji 去除变体选择符，使 "⚡️" 和 "⚡" 视为同一个 emoji
+func normalizeEmoji(emoji string) string {
+	return strings.ReplaceAll(strings.TrimSpace(emoji), "\uFE0F", "")
+}
+
+// containsEmoji 判断 emoji 是否在列表中
+func containsEmoji(list []string, emoji string) bool {
+	for _, e := range list {
+		if normalizeEmoji(e) == normalizeEmoji(emoji) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmojiToken 判断标题的第一个词是否为 emoji 或 emoji 短代码
+func isEmojiToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	if shortcodePattern.MatchString(token) {
+		return true
+	}
+	for _, r := range token {
+		if r < 0x2000 || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLeadingEmoji 拆分标题开头的 emoji 和其余文本
+func splitLeadingEmoji(subject string) (string, string) {
+	fields := strings.SplitN(strings.TrimSpace(subject), " ", 2)
+	if !isEmojiToken(fields[0]) {
+		return "", strings.TrimSpace(subject)
+	}
+	if len(fields) == 1 {
+		return fields[0], ""
+	}
+	return fields[0], strings.TrimSpace(fields[1])
+}
+
+// gitmojiHint 开启 gitmoji 时告知模型可用的 emoji
+func gitmojiHint() string {
+	if !config.Gitmoji {
+		return ""
+	}
+
+	mapping := emojiMap()
+	types := make([]string, 0, len(mapping))
+	for t := range mapping {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	pairs := make([]string, len(types))
+	for i, t := range types {
+		pairs[i] = fmt.Sprintf("%s %s", t, mapping[t])
+	}
+
+	hint := fmt.Sprintf("Start the commit subject with the emoji for its type, followed by a space. Use only this type to emoji mapping: %s.", strings.Join(pairs, ", "))
+	if len(config.DisallowedEmojis) > 0 {
+		hint += fmt.Sprintf(" Never use these emojis: %s.", strings.Join(config.DisallowedEmojis, " "))
+	}
+	return hint
+}
+
+// applyGitmoji 在本地校正标题开头的 emoji，确保只使用团队约定的 emoji
+//
+// 标题带有 Conventional Commits 类型时使用映射中该类型的 emoji；
+// 否则保留映射中已有的 emoji，去掉映射之外或被禁用的 emoji。
+func applyGitmoji(message string) string {
+	if !config.Gitmoji || message == "" {
+		return message
+	}
+
+	subject, body, hasBody := strings.Cut(message, "\n")
+	emoji, text := splitLeadingEmoji(subject)
+
+	mapping := emojiMap()
+	allowed := make([]string, 0, len(mapping))
+	for _, e := range mapping {
+		allowed = append(allowed, e)
+	}
+
+	if match := conventionalTypePattern.FindStringSubmatch(text); match != nil && mapping[match[1]] != "" && !containsEmoji(config.DisallowedEmojis, mapping[match[1]]) {
+		emoji = mapping[match[1]]
+	} else if emoji != "" && (containsEmoji(config.DisallowedEmojis, emoji) || !containsEmoji(allowed, emoji)) {
+		emoji = ""
+	}
+
+	subject = text
+	if emoji != "" {
+		subject = emoji + " " + text
+	}
+	if !hasBody {
+		return subject
+	}
+	return subject + "\n" + body
+}
+
+// validateEmojiConfig 校验 emoji_map 和 disallowed_emojis，用户配置和仓库级配置共用
+func validateEmojiConfig(mapping map[string]string, disallowed []string, lines map[string]int) []configIssue {
+	var issues []configIssue
+	for t, emoji := range mapping {
+		key := "emoji_map." + t
+		switch {
+		case strings.TrimSpace(emoji) == "":
+			issues = append(issues, configIssue{line: lines[key], key: key, message: tr("schema.empty_entry")})
+		case !isEmojiToken(emoji):
+			issues = append(issues, configIssue{line: lines[key], key: key, message: tr("schema.not_emoji", emoji)})
+		case containsEmoji(disallowed, emoji):
+			issues = append(issues, configIssue{line: lines[key], key: key, message: tr("schema.disallowed_emoji", emoji)})
+		}
+	}
+	for i, emoji := range disallowed {
+		if !isEmojiToken(emoji) {
+			issues = append(issues, configIssue{line: lines["disallowed_emojis"], key: fmt.Sprintf("disallowed_emojis[%d]", i), message: tr("schema.not_emoji", emoji)})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var gitVersionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// gitFeature 需要较新 git 的功能，旧版本中改用等价的做法
+type gitFeature struct {
+	name         string
+	major, minor int
+}
+
+var (
+	// gitPathFeature rev-parse --git-path 和 --git-common-dir，旧版本中直接使用 git 目录
+	gitPathFeature = gitFeature{"rev-parse --git-path", 2, 5}
+	// gitRenamesFeature diff 默认检测重命名，旧版本中通过 -c diff.renames=true 开启
+	gitRenamesFeature = gitFeature{"diff.renames", 2, 9}
+	// gitAbsoluteDirFeature rev-parse --absolute-git-dir，旧版本中自行转换为绝对路径
+	gitAbsoluteDirFeature = gitFeature{"rev-parse --absolute-git-dir", 2, 13}
+
+	gitFeatures = []gitFeature{gitPathFeature, gitRenamesFeature, gitAbsoluteDirFeature}
+)
+
+var (
+	gitVersionOnce     sync.Once
+	gitMajor, gitMinor int
+	gitVersionKnown    bool
+)
+
+// parseGitVersion 从 git --version 的输出中解析主版本号和次版本号
+func parseGitVersion(output string) (int, int, bool) {
+	match := gitVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0, 0, false
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return major, minor, true
+}
+
+// detectGitVersion 获取安装的 git 版本，只运行一次
+//
+// 不能通过 gitOutput 获取，gitOutput 本身要根据版本决定参数。
+func detectGitVersion() {
+	gitVersionOnce.Do(func() {
+		output, err := exec.Command("git", "--version").Output()
+		if err != nil {
+			return
+		}
+		gitMajor, gitMinor, gitVersionKnown = parseGitVersion(string(output))
+	})
+}
+
+// gitSupports 安装的 git 是否支持该功能，无法识别版本时视为支持
+func gitSupports(f gitFeature) bool {
+	detectGitVersion()
+	if !gitVersionKnown {
+		return true
+	}
+	return gitMajor > f.major || (gitMajor == f.major && gitMinor >= f.minor)
+}
+
+// unsupportedGitFeatures 返回安装的 git 不支持、改用了等价做法的功能
+func unsupportedGitFeatures() []string {
+	var names []string
+	for _, f := range gitFeatures {
+		if !gitSupports(f) {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// gitConfigOverrides 运行 git 时通过 -c 追加的配置，如纯文本输出时的 color.ui=false
+var gitConfigOverrides []string
+
+// addGitConfig 为之后运行的 git 命令追加一项配置
+//
+// 使用 -c 而不是 GIT_CONFIG_COUNT 环境变量，后者需要 git 2.31，旧版本会静默忽略。
+func addGitConfig(key, value string) {
+	gitConfigOverrides = append(gitConfigOverrides, key+"="+value)
+}
+
+// gitCompatArgs 在 git 命令前加上追加的配置，旧版本的 git 中还会加上恢复新版本默认行为的配置
+func gitCompatArgs(args []string) []string {
+	var prefix []string
+	for _, override := range gitConfigOverrides {
+		prefix = append(prefix, "-c", override)
+	}
+	if !gitSupports(gitRenamesFeature) {
+		prefix = append(prefix, "-c", "diff.renames=true")
+	}
+	if len(prefix) == 0 {
+		return args
+	}
+	return append(prefix, args...)
+}
+
+// gitDir 返回 git 目录的绝对路径
+func gitDir() (string, error) {
+	if gitSupports(gitAbsoluteDirFeature) {
+		output, err := gitOutput("rev-parse", "--absolute-git-dir")
+		return strings.TrimSpace(output), err
+	}
+	output, err := gitOutput("rev-parse", "--git-dir")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(strings.TrimSpace(output))
+}
+
+// gitCommonDir 返回工作树共用的 git 目录，旧版本的 git 没有工作树，即为 git 目录
+func gitCommonDir() (string, error) {
+	if gitSupports(gitPathFeature) {
+		output, err := gitOutput("rev-parse", "--git-common-dir")
+		return strings.TrimSpace(output), err
+	}
+	return gitDir()
+}
+
+// gitPath 返回 git 目录中文件的路径，如 hooks、COMMIT_EDITMSG
+func gitPath(name string) (string, error) {
+	if gitSupports(gitPathFeature) {
+		output, err := gitOutput("rev-parse", "--git-path", name)
+		return strings.TrimSpace(output), err
+	}
+	dir, err := gitDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
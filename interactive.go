@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// startGeneration 在后台生成提交信息，结果通过返回的通道送回
+func startGeneration(ctx promptContext, files []fileDiff) <-chan string {
+	result := make(chan string, 1)
+	go func() {
+		result <- composeMessage(ctx, files)
+	}()
+	return result
+}
+
+// showStagedDiff 通过 git 的分页器显示暂存区差异，同时在后台生成提交信息，用户退出分页器后返回
+//
+// 分页器占用终端期间，后台生成的进度等输出先暂存，出错时也不直接退出，
+// 分页器退出后再输出，避免写进分页器的画面或使终端处于异常状态。
+func showStagedDiff(ctx promptContext, files []fileDiff) <-chan string {
+	cmd := exec.Command("git", gitCompatArgs(withPathspecs("diff", "--cached"))...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	holdFailures()
+	restoreConsole := holdConsole()
+	result := startGeneration(ctx, files)
+	err := cmd.Run()
+	restoreConsole()
+
+	if held := releaseFailures(); held != nil {
+		failWith(held)
+	}
+	if err != nil {
+		fail("err.git", err)
+	}
+	return result
+}
+
+// waitForMessage 等待后台生成的提交信息，尚未完成时提示用户
+func waitForMessage(result <-chan string) string {
+	select {
+	case message := <-result:
+		return message
+	default:
+	}
+	fmt.Fprintln(stdout, tr("interactive.waiting"))
+	return <-result
+}
+
+// reviewInteractively 显示差异供用户查看，同时在后台预先生成提交信息
+//
+// 请求在差异显示的同时发出，用户看完差异时提交信息通常已经生成，无需再等待模型。
+// 返回最终的提交信息以及是否需要在编辑器中修改。
+func reviewInteractively(ctx promptContext, files []fileDiff) (string, bool) {
+	result := showStagedDiff(ctx, files)
+
+	reader := bufio.NewReader(os.Stdin)
+	var message string
+	for {
+		if result != nil {
+			message = waitForMessage(result)
+			result = nil
+			fmt.Fprintln(stdout)
+			fmt.Fprintln(stdout, message)
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprint(stdout, tr("interactive.prompt"))
+
+		answer, err := reader.ReadString('\n')
+		if err != nil && answer == "" {
+			fmt.Fprintln(stdout)
+			fmt.Fprintln(stdout, tr("interactive.aborted"))
+			os.Exit(0)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "", "y", "yes":
+			return message, false
+		case "e", "edit":
+			return message, true
+		case "r", "regenerate":
+			fmt.Fprintln(stdout, tr("interactive.regenerating"))
+			result = startGeneration(ctx, files)
+		case "n", "no", "q":
+			fmt.Fprintln(stdout, tr("interactive.aborted"))
+			os.Exit(0)
+		default:
+			fmt.Fprintln(stdout, tr("interactive.invalid", strings.TrimSpace(answer)))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// jobQueue 分段摘要等批量请求的进度，保存在仓库的状态目录中
+//
+// 每完成一段就写入状态文件，速率限制、网络错误或 Ctrl-C 中断后，使用 --resume 重新运行时
+// 直接使用已完成的结果，不再重复请求。结果按提示词和模型的哈希保存，范围或参数改变后
+// 不会误用旧的结果。整个命令成功后删除状态文件。
+type jobQueue struct {
+	path string
+
+	mu      sync.Mutex
+	Results map[string]string `json:"results"`
+}
+
+// jobStateFileName 返回批量任务的状态文件名
+func jobStateFileName(name string) string {
+	return "jobs-" + name + ".json"
+}
+
+// openJobQueue 打开批量任务的状态文件，resume 为 false 时忽略之前中断留下的进度
+func openJobQueue(name string, resume bool) *jobQueue {
+	q := &jobQueue{Results: map[string]string{}}
+	path, err := repoStatePath(jobStateFileName(name))
+	if err != nil {
+		return q
+	}
+	q.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return q
+	}
+	if !resume {
+		fmt.Fprintln(stderr, tr("jobs.interrupted"))
+		return q
+	}
+	json.Unmarshal(data, q)
+	if q.Results == nil {
+		q.Results = map[string]string{}
+	}
+	fmt.Fprintln(stderr, tr("jobs.resuming", len(q.Results)))
+	return q
+}
+
+// jobKey 返回一个请求的标识，模型不同时结果不能复用
+func jobKey(prompt string) string {
+	sum := sha256.Sum256([]byte(config.Model + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup 返回已完成的请求结果，q 为 nil 时不保存进度
+func (q *jobQueue) lookup(prompt string) (string, bool) {
+	if q == nil {
+		return "", false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	result, ok := q.Results[jobKey(prompt)]
+	return result, ok
+}
+
+// record 保存完成的请求结果，先写入临时文件再改名，避免中断时留下写了一半的文件
+func (q *jobQueue) record(prompt, result string) {
+	if q == nil || q.path == "" {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.Results[jobKey(prompt)] = result
+
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// finish 整个任务完成后删除状态文件
+func (q *jobQueue) finish() {
+	if q == nil || q.path == "" {
+		return
+	}
+	os.Remove(q.path)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// languageByExtension 文件扩展名到编程语言的映射
+var languageByExtension = map[string]string{
+	".go": "Go", ".py": "Python", ".js": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".jsx": "JavaScript (React)", ".ts": "TypeScript", ".tsx": "TypeScript (React)", ".java": "Java",
+	".kt": "Kotlin", ".kts": "Kotlin", ".rs": "Rust", ".rb": "Ruby", ".php": "PHP", ".cs": "C#",
+	".c": "C", ".h": "C", ".cpp": "C++", ".cc": "C++", ".cxx": "C++", ".hpp": "C++",
+	".swift": "Swift", ".m": "Objective-C", ".scala": "Scala", ".dart": "Dart", ".lua": "Lua",
+	".sh": "Shell", ".bash": "Shell", ".zsh": "Shell", ".ps1": "PowerShell", ".sql": "SQL",
+	".html": "HTML", ".css": "CSS", ".scss": "SCSS", ".less": "Less", ".vue": "Vue", ".svelte": "Svelte",
+	".md": "Markdown", ".rst": "reStructuredText", ".yml": "YAML", ".yaml": "YAML", ".json": "JSON",
+	".toml": "TOML", ".xml": "XML", ".tf": "Terraform", ".proto": "Protocol Buffers", ".graphql": "GraphQL",
+	".gradle": "Gradle", ".ex": "Elixir", ".exs": "Elixir", ".erl": "Erlang", ".hs": "Haskell", ".r": "R",
+}
+
+// languageByBasename 没有扩展名的常见文件
+var languageByBasename = map[string]string{
+	"Dockerfile": "Dockerfile", "Makefile": "Makefile", "Jenkinsfile": "Groovy", "Vagrantfile": "Ruby",
+	"Gemfile": "Ruby", "Rakefile": "Ruby", "CMakeLists.txt": "CMake",
+}
+
+// languageByInterpreter shebang 中的解释器到编程语言的映射
+var languageByInterpreter = map[string]string{
+	"python": "Python", "python3": "Python", "node": "JavaScript", "bash": "Shell", "sh": "Shell",
+	"zsh": "Shell", "ruby": "Ruby", "perl": "Perl", "php": "PHP",
+}
+
+// languageGuidance 针对特定文件的提示
+type languageGuidance struct {
+	match    func(file string) bool
+	guidance string
+}
+
+var languageGuidances = []languageGuidance{
+	{
+		match: func(f string) bool {
+			return strings.HasSuffix(f, ".sql") || strings.Contains(f, "migration") || strings.Contains(f, "/migrate/")
+		},
+		guidance: "The change touches SQL or database migrations: state the schema or data migration explicitly (tables, columns, indexes).",
+	},
+	{
+		match: func(f string) bool {
+			base := strings.ToLower(path.Base(f))
+			return strings.Contains(base, "router") || strings.Contains(base, "routes") || strings.Contains(base, "urls.py")
+		},
+		guidance: "The change touches routing files: mention the API routes or endpoints that were added, removed or changed.",
+	},
+	{
+		match: func(f string) bool {
+			return strings.HasSuffix(f, ".proto") || strings.HasSuffix(f, ".graphql") || strings.Contains(f, "openapi") || strings.Contains(f, "swagger")
+		},
+		guidance: "The change touches API contracts (schemas or IDL files): mention the messages, fields or operations that changed and whether they are backward compatible.",
+	},
+	{
+		match: func(f string) bool {
+			base := path.Base(f)
+			return base == "Dockerfile" || strings.HasPrefix(base, "docker-compose") || strings.HasSuffix(f, ".tf") || strings.Contains(f, "k8s/") || strings.Contains(f, "helm/")
+		},
+		guidance: "The change touches container or infrastructure definitions: describe the deployment or environment impact.",
+	},
+	{
+		match: func(f string) bool {
+			return strings.HasPrefix(f, ".github/workflows/") || f == ".gitlab-ci.yml" || strings.HasPrefix(f, ".circleci/") || path.Base(f) == "Jenkinsfile"
+		},
+		guidance: "The change touches CI pipelines: describe which build, test or release steps changed.",
+	},
+	{
+		match: func(f string) bool {
+			return strings.Contains(f, "i18n/") || strings.Contains(f, "locales/") || strings.Contains(f, "translations/")
+		},
+		guidance: "The change touches translation files: mention the affected languages or messages.",
+	},
+}
+
+// detectLanguage 根据文件名和内容判断编程语言
+func detectLanguage(f fileDiff) string {
+	base := path.Base(f.path)
+	if lang, ok := languageByBasename[base]; ok {
+		return lang
+	}
+	if lang, ok := languageByExtension[strings.ToLower(path.Ext(base))]; ok {
+		return lang
+	}
+
+	// 没有扩展名的脚本根据 shebang 判断
+	for _, line := range strings.Split(f.text, "\n") {
+		if !strings.HasPrefix(line, "+#!") && !strings.HasPrefix(line, " #!") && !strings.HasPrefix(line, "-#!") {
+			continue
+		}
+		fields := strings.Fields(line[3:])
+		if len(fields) == 0 {
+			break
+		}
+		interpreter := path.Base(fields[0])
+		if interpreter == "env" && len(fields) > 1 {
+			interpreter = fields[1]
+		}
+		if lang, ok := languageByInterpreter[interpreter]; ok {
+			return lang
+		}
+		break
+	}
+
+	return ""
+}
+
+// changedLines 统计文件差异中改动的行数
+func changedLines(f fileDiff) int {
+	n := 0
+	for _, line := range strings.Split(f.text, "\n") {
+		if (strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++ ")) || (strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "--- ")) {
+			n++
+		}
+	}
+	return n
+}
+
+// languagesHint 提示模型改动涉及的主要编程语言以及特定文件的注意事项
+func languagesHint(files []fileDiff) string {
+	weights := make(map[string]int)
+	total := 0
+	for _, f := range files {
+		lang := detectLanguage(f)
+		if lang == "" {
+			continue
+		}
+		n := changedLines(f)
+		if n == 0 {
+			n = 1
+		}
+		weights[lang] += n
+		total += n
+	}
+
+	var lines []string
+	if total > 0 {
+		langs := make([]string, 0, len(weights))
+		for lang := range weights {
+			langs = append(langs, lang)
+		}
+		sort.Slice(langs, func(i, j int) bool {
+			if weights[langs[i]] != weights[langs[j]] {
+				return weights[langs[i]] > weights[langs[j]]
+			}
+			return langs[i] < langs[j]
+		})
+		if len(langs) > 3 {
+			langs = langs[:3]
+		}
+
+		parts := make([]string, len(langs))
+		for i, lang := range langs {
+			parts[i] = fmt.Sprintf("%s (%d%%)", lang, weights[lang]*100/total)
+		}
+		lines = append(lines, fmt.Sprintf("The change is predominantly in %s.", strings.Join(parts, ", ")))
+	}
+
+	for _, g := range languageGuidances {
+		for _, f := range files {
+			if g.match(f.path) {
+				lines = append(lines, g.guidance)
+				break
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
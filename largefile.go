@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFileDiffChars 单个文件差异在提示词中的最大字符数，默认为 max_diff_chars 的一半
+func maxFileDiffChars() int {
+	if config.MaxFileDiffChars > 0 {
+		return config.MaxFileDiffChars
+	}
+	return maxDiffChars() / 2
+}
+
+// splitHunks 将文件差异拆分为头部和各个 hunk
+func splitHunks(text string) (string, []string) {
+	var header []string
+	var hunks []string
+	var current []string
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			if current != nil {
+				hunks = append(hunks, strings.Join(current, "\n"))
+			}
+			current = []string{line}
+			continue
+		}
+		if current == nil {
+			header = append(header, line)
+		} else {
+			current = append(current, line)
+		}
+	}
+	if current != nil {
+		hunks = append(hunks, strings.Join(current, "\n"))
+	}
+
+	return strings.Join(header, "\n"), hunks
+}
+
+// diffStat 统计文件差异增删的行数，与 git diff --stat 一致
+func diffStat(hunks []string) (int, int) {
+	added, deleted := 0, 0
+	for _, hunk := range hunks {
+		for _, line := range strings.Split(hunk, "\n") {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				added++
+			case strings.HasPrefix(line, "-"):
+				deleted++
+			}
+		}
+	}
+	return added, deleted
+}
+
+// truncateHunk 截断过长的 hunk，只保留前 budget 个字符内的完整行
+//
+// 第一行就超出预算时在字符边界处截断，避免把多字节的 UTF-8 字符截成两半。
+func truncateHunk(hunk string, budget int) string {
+	if len(hunk) <= budget {
+		return hunk
+	}
+	cut := strings.LastIndex(hunk[:budget], "\n")
+	if cut <= 0 {
+		cut = runeBoundary(hunk, budget)
+	}
+	return hunk[:cut] + "\n" + tr("summary.file_truncated")
+}
+
+// runeBoundary 返回不超过 n 的最大字符边界
+func runeBoundary(text string, n int) int {
+	if n >= len(text) {
+		return len(text)
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return n
+}
+
+// condenseLargeFile 将超出单文件预算的差异压缩为统计信息加首尾两个 hunk
+//
+// 生成的 protobuf 代码、锁文件等单个文件的差异可能超过整个预算，
+// 完整放入提示词会挤掉其他文件的改动。
+func condenseLargeFile(f fileDiff, budget int) string {
+	header, hunks := splitHunks(f.text)
+	added, deleted := diffStat(hunks)
+
+	var b strings.Builder
+	b.WriteString(header)
+	fmt.Fprintf(&b, "\n%s | %d insertions(+), %d deletions(-), %d hunks\n", f.path, added, deleted, len(hunks))
+
+	if len(hunks) == 0 {
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+
+	hunkBudget := budget / 2
+	b.WriteString(truncateHunk(hunks[0], hunkBudget))
+	if len(hunks) > 1 {
+		if len(hunks) > 2 {
+			fmt.Fprintf(&b, "\n... (%d hunks omitted)", len(hunks)-2)
+		}
+		b.WriteString("\n" + truncateHunk(hunks[len(hunks)-1], hunkBudget))
+	}
+
+	return b.String()
+}
+
+// condensedFilesHint 告知模型哪些文件的差异被压缩
+func condensedFilesHint(condensed []string) string {
+	if len(condensed) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("The diffs of these files were too large and only their line counts plus first and last hunks are shown: %s. Describe these files at a high level (for example regenerated code or a bulk update) instead of guessing the omitted details, and do not let them overshadow the other changes.", strings.Join(condensed, ", "))
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultLearnCount   = 20
+	learnSampleSize     = 200
+	minStyleSampleScore = 3
+)
+
+// conventionalPattern 匹配 Conventional Commits 风格的标题
+var conventionalPattern = regexp.MustCompile(`^[a-z]+(\([^)]+\))?!?: \S`)
+
+// lowQualitySubjects 低质量的提交标题，学习时跳过
+var lowQualitySubjects = []string{
+	"update", "updates", "fix", "fixes", "wip", "test", "tmp", "temp",
+	"update code", "fix bug", "changes", "minor changes", "misc",
+}
+
+// commitSample 提交样本
+type commitSample struct {
+	hash    string
+	message string
+	score   int
+}
+
+func runLearn(args []string) {
+	count := defaultLearnCount
+
+	for _, arg := range args {
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if strings.HasPrefix(arg, "--count=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--count="))
+			if err != nil || n <= 0 {
+				fail("arg.invalid_count", arg)
+			}
+			count = n
+		} else {
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	ensureGitRepository()
+
+	if err := loadConfig(); err != nil {
+		fail("err.load_config", err)
+	}
+
+	fmt.Fprintln(stdout, tr("learn.sampling"))
+	samples := selectStyleSamples(getRecentCommits(learnSampleSize), count)
+	if len(samples) == 0 {
+		fail("learn.none")
+	}
+	fmt.Fprintln(stdout, tr("learn.learning", len(samples)))
+
+	guide, err := requestCompletion(buildLearnPrompt(samples))
+	if err != nil {
+		failWith(err)
+	}
+	if guide == "" {
+		fail("learn.unable")
+	}
+
+	if err := loadRepoConfig(); err != nil {
+		fail("err.load_repo_config", err)
+	}
+	repoConfig.StyleGuide = guide
+	configPath, err := saveRepoConfig()
+	if err != nil {
+		fail("err.save_repo_config", err)
+	}
+
+	fmt.Fprintln(stdout, tr("learn.saved", configPath))
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, guide)
+}
+
+// getRecentCommits 获取最近的非合并提交
+func getRecentCommits(n int) []commitSample {
+	output := runGitCommand("log", "-n", strconv.Itoa(n), "--no-merges", "--format=%H%x00%B%x1e")
+
+	var commits []commitSample
+	for _, record := range strings.Split(output, "\x1e") {
+		parts := strings.SplitN(strings.TrimSpace(record), "\x00", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		commits = append(commits, commitSample{
+			hash:    parts[0],
+			message: strings.TrimSpace(parts[1]),
+		})
+	}
+
+	return commits
+}
+
+// scoreCommitMessage 根据长度和结构给提交信息打分
+func scoreCommitMessage(msg string) int {
+	lines := strings.Split(msg, "\n")
+	subject := strings.TrimSpace(lines[0])
+	length := utf8.RuneCountInString(subject)
+	if length < 10 {
+		return 0
+	}
+
+	lower := strings.ToLower(subject)
+	for _, s := range lowQualitySubjects {
+		if lower == s {
+			return 0
+		}
+	}
+	if strings.HasPrefix(lower, "wip") || strings.HasPrefix(lower, "fixup!") || strings.HasPrefix(lower, "squash!") {
+		return 0
+	}
+
+	score := 1
+	if length <= 72 {
+		score += 2
+	}
+	if conventionalPattern.MatchString(subject) {
+		score += 2
+	}
+	if !strings.HasSuffix(subject, ".") {
+		score++
+	}
+	// 标题与正文之间有空行
+	if len(lines) > 2 && strings.TrimSpace(lines[1]) == "" {
+		score += 2
+	}
+
+	return score
+}
+
+// selectStyleSamples 选出得分最高的 n 个提交
+func selectStyleSamples(commits []commitSample, n int) []commitSample {
+	var candidates []commitSample
+	for _, c := range commits {
+		c.score = scoreCommitMessage(c.message)
+		if c.score >= minStyleSampleScore {
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+
+	return candidates
+}
+
+// buildLearnPrompt 构建提炼风格指南的提示词
+func buildLearnPrompt(samples []commitSample) string {
+	data := learnPromptData{Samples: make([]string, len(samples))}
+	for i, s := range samples {
+		data.Samples[i] = s.message
+	}
+	return renderTemplate("learn", data)
+}
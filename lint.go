@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultMaxSubjectLength = 72
+
+	formatGitHub = "github"
+)
+
+// LintConfig aicommit lint 的检查规则，配置在仓库级配置中供整个团队共用
+type LintConfig struct {
+	MaxSubjectLength int      `json:"max_subject_length,omitempty"`
+	Conventional     bool     `json:"conventional,omitempty"`
+	Types            []string `json:"types,omitempty"`
+	Scopes           []string `json:"scopes,omitempty"`
+	RequireBody      bool     `json:"require_body,omitempty"`
+	AllowFixup       bool     `json:"allow_fixup,omitempty"`
+	LLM              bool     `json:"llm,omitempty"`
+}
+
+// lintCommit 待检查的提交
+type lintCommit struct {
+	hash    string
+	message string
+}
+
+// lintIssue 提交信息中的一个问题
+type lintIssue struct {
+	commit  lintCommit
+	rule    string
+	message string
+}
+
+func runLint(args []string) {
+	revRange := ""
+	format := formatText
+	llm := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if arg == "--llm" {
+			llm = true
+		} else if value, ok := flagValue(args, &i, "--format"); ok {
+			format = value
+		} else if strings.HasPrefix(arg, "-") || revRange != "" {
+			failUsage("arg.unknown", arg)
+		} else {
+			revRange = arg
+		}
+	}
+
+	if format != formatText && format != formatGitHub {
+		fail("arg.invalid_choice", "--format", format, formatText+"|"+formatGitHub)
+	}
+
+	ensureGitRepository()
+
+	// 只做规则检查时不需要 API 密钥，CI 中可以没有用户配置
+	if err := loadRepoConfig(); err != nil {
+		fail("err.load_repo_config", err)
+	}
+	rules := LintConfig{}
+	if repoConfig.Lint != nil {
+		rules = *repoConfig.Lint
+	}
+	llm = llm || rules.LLM
+	if llm {
+		if err := loadConfig(); err != nil {
+			fail("err.load_config", err)
+		}
+	} else if configPath, err := getConfigFilePath(); err == nil {
+		if _, err := os.Stat(configPath); err == nil {
+			if err := readConfig(configPath); err != nil {
+				fail("err.load_config", err)
+			}
+		}
+	}
+	applyRepoConfig()
+
+	if revRange == "" {
+		revRange = defaultLintRange()
+	}
+	commits := getLintCommits(revRange)
+	if len(commits) == 0 {
+		fmt.Fprintln(stdout, tr("lint.none", revRange))
+		os.Exit(0)
+	}
+
+	var issues []lintIssue
+	for _, c := range commits {
+		for _, issue := range lintMessage(c.message, rules) {
+			issue.commit = c
+			issues = append(issues, issue)
+		}
+	}
+
+	if llm {
+		fmt.Fprintln(stderr, tr("lint.judging", len(commits)))
+		judged, err := judgeCommitMessages(commits)
+		if err != nil {
+			failWith(err)
+		}
+		issues = append(issues, judged...)
+
+		// 模型给出的问题排在对应提交的规则问题之后
+		order := make(map[string]int, len(commits))
+		for i, c := range commits {
+			order[c.hash] = i
+		}
+		sort.SliceStable(issues, func(i, j int) bool {
+			return order[issues[i].commit.hash] < order[issues[j].commit.hash]
+		})
+	}
+
+	printLintIssues(issues, format)
+
+	if len(issues) > 0 {
+		fail("lint.failed", len(issues), len(commits))
+	}
+	fmt.Fprintln(stdout, tr("lint.passed", len(commits)))
+}
+
+// defaultLintRange 未指定范围时检查上游分支之后的提交，没有上游分支时只检查 HEAD
+func defaultLintRange() string {
+	if _, err := gitOutput("rev-parse", "--verify", "-q", "@{upstream}"); err == nil {
+		return "@{upstream}..HEAD"
+	}
+	return "HEAD^!"
+}
+
+// getLintCommits 获取范围内的非合并提交，从旧到新排列
+func getLintCommits(revRange string) []lintCommit {
+	output := normalizeNewlines(runGitCommand("log", "--no-merges", "--reverse", "--format=%h%x00%B%x1e", revRange))
+
+	var commits []lintCommit
+	for _, record := range strings.Split(output, "\x1e") {
+		parts := strings.SplitN(strings.TrimSpace(record), "\x00", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		commits = append(commits, lintCommit{hash: parts[0], message: strings.TrimSpace(parts[1])})
+	}
+	return commits
+}
+
+// lintTypes 返回允许的 Conventional Commits 类型，未配置时使用 gitmoji 映射中的类型
+func lintTypes(rules LintConfig) []string {
+	if len(rules.Types) > 0 {
+		return rules.Types
+	}
+	types := make([]string, 0, len(defaultEmojiMap))
+	for t := range defaultEmojiMap {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// lintMessage 按规则检查一条提交信息
+func lintMessage(message string, rules LintConfig) []lintIssue {
+	var issues []lintIssue
+	add := func(rule, key string, args ...interface{}) {
+		issues = append(issues, lintIssue{rule: rule, message: tr(key, args...)})
+	}
+
+	lines := strings.Split(message, "\n")
+	subject := strings.TrimSpace(lines[0])
+	if subject == "" {
+		add("subject-empty", "lint.subject_empty")
+		return issues
+	}
+
+	lower := strings.ToLower(subject)
+	if strings.HasPrefix(lower, "fixup!") || strings.HasPrefix(lower, "squash!") || strings.HasPrefix(lower, "amend!") {
+		if !rules.AllowFixup {
+			add("fixup", "lint.fixup")
+		}
+		return issues
+	}
+
+	maxLength := rules.MaxSubjectLength
+	if maxLength == 0 {
+		maxLength = defaultMaxSubjectLength
+	}
+	if length := utf8.RuneCountInString(subject); length > maxLength {
+		add("subject-length", "lint.subject_length", length, maxLength)
+	}
+	if strings.HasSuffix(subject, ".") || strings.HasSuffix(subject, "。") {
+		add("subject-period", "lint.subject_period")
+	}
+	if containsString(lowQualitySubjects, strings.TrimRight(lower, ".。")) || strings.HasPrefix(lower, "wip") {
+		add("subject-vague", "lint.subject_vague")
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		add("body-separator", "lint.body_separator")
+	}
+	if rules.RequireBody && strings.TrimSpace(strings.Join(lines[1:], "\n")) == "" {
+		add("body-required", "lint.body_required")
+	}
+
+	_, text := splitLeadingEmoji(subject)
+	if rules.Conventional {
+		match := conventionalTypePattern.FindStringSubmatch(text)
+		if match == nil || !conventionalPattern.MatchString(text) {
+			add("conventional", "lint.conventional")
+		} else if types := lintTypes(rules); !containsString(types, match[1]) {
+			add("conventional", "lint.type", match[1], strings.Join(types, ", "))
+		} else if scope := conventionalScope(text); len(rules.Scopes) > 0 && scope != "" && !containsString(rules.Scopes, scope) {
+			add("scope", "lint.scope", scope, strings.Join(rules.Scopes, ", "))
+		}
+		if _, bang, footer := splitBreakingMarkers(message); bang != footer {
+			add("breaking", "lint.breaking")
+		}
+	}
+	if fixed := applyGitmoji(subject); fixed != subject {
+		add("gitmoji", "lint.gitmoji", fixed)
+	}
+	if fixed := applySubjectStyle(subject, config.DefaultLang); fixed != subject {
+		add("subject-style", "lint.subject_style", fixed)
+	}
+
+	return issues
+}
+
+// conventionalScope 返回 Conventional Commits 标题中的 scope
+func conventionalScope(subject string) string {
+	start := strings.Index(subject, "(")
+	end := strings.Index(subject, ")")
+	colon := strings.Index(subject, ":")
+	if start < 0 || end < start || colon < end {
+		return ""
+	}
+	return subject[start+1 : end]
+}
+
+// judgeCommitMessages 请模型对照风格指南和改动文件评判提交信息，一次请求评判所有提交
+func judgeCommitMessages(commits []lintCommit) ([]lintIssue, error) {
+	content, err := requestCompletion(buildLintPrompt(commits))
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string]lintCommit, len(commits))
+	for _, c := range commits {
+		byHash[c.hash] = c
+	}
+
+	var issues []lintIssue
+	for _, line := range strings.Split(content, "\n") {
+		hash, verdict, found := strings.Cut(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "- ")), ":")
+		c, ok := byHash[strings.TrimSpace(hash)]
+		verdict = strings.TrimSpace(verdict)
+		if !found || !ok || verdict == "" || strings.EqualFold(verdict, "OK") {
+			continue
+		}
+		issues = append(issues, lintIssue{commit: c, rule: "llm", message: verdict})
+	}
+	return issues, nil
+}
+
+// buildLintPrompt 构建评判提交信息的提示词
+func buildLintPrompt(commits []lintCommit) string {
+	data := lintPromptData{StyleGuide: repoConfig.StyleGuide, Lang: config.DefaultLang}
+	for _, c := range commits {
+		stat, _ := gitOutput("show", "--stat", "--format=", c.hash)
+		data.Commits = append(data.Commits, lintPromptCommit{
+			Hash:    c.hash,
+			Message: c.message,
+			Stat:    strings.TrimRight(normalizeNewlines(stat), "\n"),
+		})
+	}
+	return renderTemplate("lint", data)
+}
+
+// printLintIssues 输出问题，github 格式使用 GitHub Actions 的工作流命令生成注解
+func printLintIssues(issues []lintIssue, format string) {
+	last := ""
+	for _, issue := range issues {
+		subject := messageSubject(issue.commit.message)
+		if format == formatGitHub {
+			fmt.Fprintf(stdout, "::error title=aicommit lint (%s)::%s\n", issue.rule, githubEscape(fmt.Sprintf("%s %s: %s", issue.commit.hash, subject, issue.message)))
+			continue
+		}
+		if issue.commit.hash != last {
+			fmt.Fprintf(stdout, "%s %s\n", issue.commit.hash, subject)
+			last = issue.commit.hash
+		}
+		fmt.Fprintf(stdout, "  %s: %s\n", issue.rule, issue.message)
+	}
+}
+
+// githubEscape 转义工作流命令消息中的特殊字符
+func githubEscape(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// validateLintConfig 校验仓库级配置中的 lint
+func validateLintConfig(rules *LintConfig, lines map[string]int) []configIssue {
+	if rules == nil || rules.MaxSubjectLength >= 0 {
+		return nil
+	}
+	return []configIssue{{line: lines["lint.max_subject_length"], key: "lint.max_subject_length", message: tr("schema.negative")}}
+}
@@ -3,19 +3,21 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf16"
 )
 
 const (
-	configDirName  = ".aicommit"
-	configFileName = "config.json"
+	configDirName   = ".aicommit"
+	configFileName  = "config.json"
+	defaultEndpoint = "https://api.openai.com/v1/chat/completions"
 )
 
 // Config 配置结构体
@@ -24,49 +26,192 @@ type Config struct {
 	APIKey         string  `json:"api_key"`
 	DefaultLang    string  `json:"default_lang"`
 	ProxyURL       string  `json:"proxy_url,omitempty"`
+	ProxyUsername  string  `json:"proxy_username,omitempty"`
+	ProxyPassword  string  `json:"proxy_password,omitempty"`
+	ProxyFromEnv   *bool   `json:"proxy_from_env,omitempty"`
 	Model          string  `json:"model"`
 	MaxTokens      int     `json:"max_tokens"`
 	Temperature    float64 `json:"temperature"`
-}
+	UILang         string  `json:"ui_lang,omitempty"`
 
-var (
-	config     Config
-	extraNotes string
-)
+	TopP       *float64 `json:"top_p,omitempty"`
+	Timeout    int      `json:"timeout,omitempty"`
+	Retries    int      `json:"retries,omitempty"`
+	RetryDelay int      `json:"retry_delay,omitempty"`
 
-type openAIRequest struct {
-	Model       string    `json:"model"`
-	Messages    []message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens"`
-	Temperature float64   `json:"temperature"`
-}
+	OpenAIEndpoints  []string `json:"openai_endpoints,omitempty"`
+	EndpointStrategy string   `json:"endpoint_strategy,omitempty"`
 
-type message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
+	ResponseContentPath string             `json:"response_content_path,omitempty"`
+	PromptCache         string             `json:"prompt_cache,omitempty"`
+	CommitAs            string             `json:"commit_as,omitempty"`
+	Profile             string             `json:"profile,omitempty"`
+	Profiles            map[string]Profile `json:"profiles,omitempty"`
 
-type openAIResponse struct {
-	Choices []choice       `json:"choices"`
-	Error   *errorResponse `json:"error,omitempty"`
-}
+	AuthProvider  string                  `json:"auth_provider,omitempty"`
+	AuthProviders map[string]AuthProvider `json:"auth_providers,omitempty"`
 
-type choice struct {
-	Message message `json:"message"`
-}
+	ScopeSource string            `json:"scope_source,omitempty"`
+	ScopeMap    map[string]string `json:"scope_map,omitempty"`
+	OwnerScopes map[string]string `json:"owner_scopes,omitempty"`
+
+	NewFileHeadLines int `json:"new_file_head_lines,omitempty"`
+	DedupHistory     int `json:"dedup_history,omitempty"`
+	EditExamples     int `json:"edit_examples,omitempty"`
+	FeedbackExamples int `json:"feedback_examples,omitempty"`
+	MaxDiffChars     int `json:"max_diff_chars,omitempty"`
+	MaxFileDiffChars int `json:"max_file_diff_chars,omitempty"`
+	MaxParallel      int `json:"max_parallel,omitempty"`
+	RefinePasses     int `json:"refine_passes,omitempty"`
+
+	SpellCheck      string `json:"spell_check,omitempty"`
+	SpellDictionary string `json:"spell_dictionary,omitempty"`
+
+	StructuralDiff   bool   `json:"structural_diff,omitempty"`
+	IncludeGenerated bool   `json:"include_generated,omitempty"`
+	IncludeVendored  bool   `json:"include_vendored,omitempty"`
+	DailyContext     bool   `json:"daily_context,omitempty"`
+	VCS              string `json:"vcs,omitempty"`
 
-type errorResponse struct {
-	Message string `json:"message"`
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+
+	ProtectedBranches []string `json:"protected_branches,omitempty"`
+
+	AllowedEndpoints  []string `json:"allowed_endpoints,omitempty"`
+	SensitiveFiles    []string `json:"sensitive_files,omitempty"`
+	DiffHashTrailer   bool     `json:"diff_hash_trailer,omitempty"`
+	IncludeTestPlan   bool     `json:"include_test_plan,omitempty"`
+	DiffTable         bool     `json:"diff_table,omitempty"`
+	HeuristicFastPath bool     `json:"heuristic_fast_path,omitempty"`
+	OriginReference   string   `json:"origin_reference,omitempty"`
+
+	Gitmoji          bool              `json:"gitmoji,omitempty"`
+	EmojiMap         map[string]string `json:"emoji_map,omitempty"`
+	DisallowedEmojis []string          `json:"disallowed_emojis,omitempty"`
+
+	SubjectCase string `json:"subject_case,omitempty"`
+	Tense       string `json:"tense,omitempty"`
+
+	WarnLargeCommitFiles int `json:"warn_large_commit_files,omitempty"`
+
+	Trailers map[string]string `json:"trailers,omitempty"`
+
+	Offline bool `json:"offline,omitempty"`
 }
 
+var (
+	config     Config
+	extraNotes string
+)
+
 // 命令行参数结构体
 type cmdArgs struct {
-	lang     string
-	notes    string
-	showHelp bool
+	lang           string
+	notes          string
+	model          string
+	temperature    *float64
+	maxTokens      int
+	maxParallel    int
+	deadline       time.Duration
+	edit           bool
+	interactive    bool
+	copy           bool
+	noCommit       bool
+	paths          []string
+	allowSensitive bool
+	structuralDiff bool
+	breaking       bool
+	breakingNote   string
+	goal           *string
+	showHelp       bool
 }
 
 func main() {
+	// 选择界面语言和输出模式
+	initUILang()
+	initPlainOutput()
+	initErrorFormat()
+
+	// 处理 -C/--chdir 等全局参数
+	os.Args = append(os.Args[:1], applyGlobalOptions(os.Args[1:])...)
+
+	// 子命令分发
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "learn":
+			setStage("learn")
+			runLearn(os.Args[2:])
+			return
+		case "doctor":
+			setStage("doctor")
+			runDoctor(os.Args[2:])
+			return
+		case "release-notes":
+			setStage("release-notes")
+			runReleaseNotes(os.Args[2:])
+			return
+		case "digest":
+			setStage("digest")
+			runDigest(os.Args[2:])
+			return
+		case "describe":
+			setStage("describe")
+			runDescribe(os.Args[2:])
+			return
+		case "feedback":
+			setStage("feedback")
+			runFeedback(os.Args[2:])
+			return
+		case "stats":
+			setStage("stats")
+			runStats(os.Args[2:])
+			return
+		case "push-check":
+			setStage("push-check")
+			runPushCheck(os.Args[2:])
+			return
+		case "lint":
+			setStage("lint")
+			runLint(os.Args[2:])
+			return
+		case "fixup":
+			setStage("fixup")
+			runFixup(os.Args[2:])
+			return
+		case "auth":
+			setStage("auth")
+			runAuth(os.Args[2:])
+			return
+		case "config":
+			setStage("config")
+			runConfig(os.Args[2:])
+			return
+		case "pr-comments":
+			setStage("pr-comments")
+			runPRComments(os.Args[2:])
+			return
+		case "compare":
+			setStage("compare")
+			runCompare(os.Args[2:])
+			return
+		case "bench":
+			setStage("bench")
+			runBench(os.Args[2:])
+			return
+		case "daemon":
+			setStage("daemon")
+			runDaemon(os.Args[2:])
+			return
+		}
+
+		// 其他子命令交给 PATH 中的 aicommit-<name> 插件
+		if path, ok := findPlugin(os.Args[1]); ok {
+			setStage("plugin")
+			loadOfflineSetting()
+			runPlugin(path, os.Args[2:])
+		}
+	}
+
 	// 先解析命令行参数，只检查 --help
 	args := parseArgs()
 	if args.showHelp {
@@ -74,44 +219,344 @@ func main() {
 		os.Exit(0)
 	}
 
+	runCommit(args)
+}
+
+// runCommit 为当前的改动生成提交信息并提交
+func runCommit(args cmdArgs) {
+	// 检查是否在 git、jj 或 hg 仓库中
+	setStage("git")
+	ensureRepository()
+
 	// 加载配置文件
+	setStage("config")
 	if err := loadConfig(); err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
-		os.Exit(1)
+		fail("err.load_config", err)
 	}
 
+	applyVCSConfig()
+
 	// 应用命令行参数覆盖配置
 	if args.lang != "" {
 		config.DefaultLang = args.lang
 	}
+	if args.model != "" {
+		config.Model = args.model
+	}
+	if args.temperature != nil {
+		config.Temperature = *args.temperature
+	}
+	if args.maxTokens > 0 {
+		config.MaxTokens = args.maxTokens
+	}
+	if args.maxParallel > 0 {
+		config.MaxParallel = args.maxParallel
+	}
+	if args.structuralDiff {
+		config.StructuralDiff = true
+	}
 	extraNotes = args.notes
+	pathspecs = args.paths
+
+	// 加载仓库级配置
+	if err := loadRepoConfig(); err != nil {
+		fail("err.load_repo_config", err)
+	}
+	applyRepoConfig()
+
+	// 拒绝自动暂存密钥等敏感文件
+	setStage("stage")
+	checkSensitiveFiles(currentVCS.pendingFiles(true), args.allowSensitive)
 
 	// 添加所有更改到暂存区
-	runGitCommand("add", ".")
+	currentVCS.stage()
 	// 检查 Git 状态
-	fmt.Println("Checking the status of the working directory...")
-	runGitCommand("status")
+	fmt.Fprintln(stdout, tr("commit.checking_status"))
+	currentVCS.refresh()
 
 	// 获取 Git 差异
-	diff := getGitDiff()
+	setStage("diff")
+	diff := currentVCS.diff()
 	if diff == "" {
-		fmt.Println("No differences found.")
+		fmt.Fprintln(stdout, tr("commit.no_diff"))
 		os.Exit(0)
 	}
 
-	// 生成提交信息
-	commitMessage := generateCommitMessage(diff, config.DefaultLang, extraNotes)
-	if commitMessage == "" {
-		fmt.Println("Unable to generate commit message.")
-		os.Exit(1)
+	// 记录生成提交信息时暂存区差异的哈希
+	diffHash := ""
+	if config.DiffHashTrailer && currentVCS.name() == vcsGit {
+		diffHash = stagedDiffHash()
 	}
 
-	// 提交更改
-	commitChanges(commitMessage)
+	// --deadline 从调用模型开始计时
+	if args.deadline > 0 {
+		generationDeadline = time.Now().Add(args.deadline)
+	}
+
+	prepared := prepareDiff(diff)
+	ctx := newPromptContext(prepared)
+	ctx.breaking = args.breaking
+	ctx.breakingNote = args.breakingNote
+	ctx.goal = dailyGoal(args.goal)
+	warnLargeCommit(ctx)
+
+	// 生成提交信息，交互模式下在用户查看差异的同时后台生成
+	setStage("generate")
+	edit := args.edit
+	var commitMessage string
+	if args.interactive {
+		commitMessage, edit = reviewInteractively(ctx, prepared.files)
+	} else {
+		commitMessage = composeMessage(ctx, prepared.files)
+	}
+
+	// 按 diff_table 在正文中附上每个文件的增删行数和说明
+	commitMessage = appendDiffTable(commitMessage, ctx)
+
+	// cherry-pick 和 revert 时引用原提交
+	commitMessage = appendOriginReference(commitMessage, ctx.sequencer)
+
+	if diffHash != "" {
+		commitMessage = appendTrailer(commitMessage, diffHashTrailerKey, diffHash)
+	}
+
+	// 按 trailers 配置追加团队工具需要的 trailer
+	commitMessage = applyConfigTrailers(commitMessage)
+
+	// --no-commit 时只输出提交信息，由其他工具完成提交
+	if args.noCommit {
+		if args.copy {
+			copyMessage(commitMessage)
+		}
+		fmt.Fprintln(stdout, tr("commit.not_committed"))
+		fmt.Fprintln(stdout)
+		fmt.Fprintln(stdout, commitMessage)
+		return
+	}
+
+	// 提交更改，--edit 时先在编辑器中确认
+	setStage("commit")
+	warnStrippedLines(commitMessage, edit)
+	currentVCS.commit(commitMessage, edit)
+
+	// 输出 git 清理后实际保存的信息，在编辑器中修改过时记录下来供之后参考
+	committed := committedMessage()
+	if edit {
+		recordEdit(commitMessage, committed)
+	}
+	commitMessage = committed
+	if args.copy {
+		copyMessage(commitMessage)
+	}
+	fmt.Fprintln(stdout, tr("commit.complete"))
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, commitMessage)
+}
+
+// copyMessage 将提交信息复制到剪贴板，失败时只给出警告
+func copyMessage(message string) {
+	if err := copyToClipboard(message); err != nil {
+		fmt.Fprintln(stdout, tr("clipboard.failed", err))
+		return
+	}
+	fmt.Fprintln(stdout, tr("clipboard.copied"))
+}
+
+// newPromptContext 根据处理后的差异构建生成提交信息的上下文
+func newPromptContext(prepared preparedDiff) promptContext {
+	return promptContext{
+		diff:        prepared.text,
+		stat:        diffStatPreamble(),
+		lang:        config.DefaultLang,
+		notes:       extraNotes,
+		files:       currentVCS.changedFiles(),
+		diffs:       prepared.files,
+		newFiles:    prepared.newFiles,
+		condensed:   prepared.condensed,
+		structural:  prepared.structural,
+		skipped:     prepared.skipped,
+		generated:   prepared.generated,
+		vendored:    prepared.vendored,
+		restructure: prepared.restructure,
+		sequencer:   detectSequencer(),
+	}
+}
+
+// composeMessage 根据差异生成提交信息，差异过大时先分段摘要
+func composeMessage(ctx promptContext, files []fileDiff) string {
+	// 离线模式下服务商不在本机时只能在本地生成
+	if offlineFallback() {
+		fmt.Fprintln(stdout, tr("offline.heuristic"))
+		return applyGitmoji(applySubjectStyle(heuristicMessage(ctx), ctx.lang))
+	}
+
+	// 类型明确时可以不调用模型，cherry-pick 和 revert 需要参考原提交信息
+	if config.HeuristicFastPath && ctx.sequencer == nil {
+		if typ, scope := detectCommitType(ctx.files); typ != "" {
+			fmt.Fprintln(stdout, tr("commit.fast_path", strings.TrimSuffix(typ+"("+scope+")", "()")))
+			return applyGitmoji(applySubjectStyle(heuristicMessage(ctx), ctx.lang))
+		}
+	}
+
+	var message string
+	if len(ctx.diff) > maxDiffChars() {
+		ctx.summarized = true
+		summary, err := summarizeDiff(files)
+		switch {
+		case errors.Is(err, errDeadlineExceeded):
+			message = deadlineFallback(ctx, "")
+		case err != nil:
+			failWith(err)
+		default:
+			ctx.diff = summary
+		}
+	}
+
+	if message == "" {
+		message = generateCommitMessage(ctx)
+		if message != "" && config.RefinePasses > 0 {
+			message = refineMessage(ctx, message)
+		}
+	}
+	if message == "" {
+		fail("commit.unable")
+	}
+
+	// 以删除或移动文件为主时，标题需要说明意图
+	if ctx.sequencer == nil && !describesRestructure(message, ctx.restructure, ctx.lang) {
+		fmt.Fprintln(stdout, tr("commit.restructure_fallback", messageSubject(message)))
+		message = restructureMessage(ctx)
+	}
+	message = applyBreakingChange(message, ctx.breaking, ctx.breakingNote)
+	message = spellCheckMessage(message, ctx.lang, ctx.diff)
+	message = applySubjectStyle(message, ctx.lang)
+
+	// 校正 gitmoji，确保只使用约定的 emoji
+	return applyGitmoji(message)
+}
+
+// applyGlobalOptions 处理 -C <path>/--chdir=<path>、--trust-endpoint 等全局参数并返回剩余参数
+//
+// 与 git 一样，多个 -C 会依次生效，每个路径都相对于上一个目录。
+func applyGlobalOptions(args []string) []string {
+	// 钩子等场景下 git 传入的路径可能是相对路径，切换目录前先转为绝对路径
+	absGitEnv()
+
+	var rest []string
+	gitEnv := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		dir := ""
+		switch {
+		case arg == "--":
+			rest = append(rest, args[i:]...)
+			i = len(args)
+			continue
+		case arg == "-C" || arg == "--chdir" || arg == "--git-dir" || arg == "--work-tree" || arg == "--profile":
+			if i+1 >= len(args) {
+				fail("arg.missing_value", arg)
+			}
+			i++
+			if name, ok := gitPathOptions[arg]; ok {
+				gitEnv[name] = args[i]
+				continue
+			}
+			if arg == "--profile" {
+				profileFlag = args[i]
+				continue
+			}
+			dir = args[i]
+		case strings.HasPrefix(arg, "--chdir="):
+			dir = strings.TrimPrefix(arg, "--chdir=")
+		case strings.HasPrefix(arg, "-C") && len(arg) > 2:
+			dir = arg[2:]
+		case strings.HasPrefix(arg, "--git-dir="):
+			gitEnv["GIT_DIR"] = strings.TrimPrefix(arg, "--git-dir=")
+			continue
+		case strings.HasPrefix(arg, "--work-tree="):
+			gitEnv["GIT_WORK_TREE"] = strings.TrimPrefix(arg, "--work-tree=")
+			continue
+		case strings.HasPrefix(arg, "--profile="):
+			profileFlag = strings.TrimPrefix(arg, "--profile=")
+			continue
+		case arg == "--trust-endpoint":
+			trustEndpoint = true
+			continue
+		case arg == "--offline":
+			offlineFlag = true
+			continue
+		case arg == "--plain":
+			enablePlainOutput()
+			continue
+		case strings.HasPrefix(arg, "--error-format="):
+			setErrorFormat(strings.TrimPrefix(arg, "--error-format="))
+			continue
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+
+		if err := os.Chdir(dir); err != nil {
+			fail("err.chdir", dir, err)
+		}
+	}
+
+	// 与 git 相同，--git-dir/--work-tree 的相对路径基于 -C 切换后的目录
+	for name, value := range gitEnv {
+		if abs, err := filepath.Abs(value); err == nil {
+			value = abs
+		}
+		os.Setenv(name, value)
+	}
+
+	return rest
+}
+
+// gitPathOptions 全局参数与对应的 git 环境变量，通过环境变量传给所有 git 子进程
+var gitPathOptions = map[string]string{
+	"--git-dir":   "GIT_DIR",
+	"--work-tree": "GIT_WORK_TREE",
+}
+
+// gitPathEnvVars 指定仓库位置的 git 环境变量
+var gitPathEnvVars = []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE", "GIT_COMMON_DIR", "GIT_OBJECT_DIRECTORY"}
+
+// absGitEnv 将 git 环境变量中的相对路径转为绝对路径
+func absGitEnv() {
+	for _, name := range gitPathEnvVars {
+		value := os.Getenv(name)
+		if value == "" || filepath.IsAbs(value) {
+			continue
+		}
+		if abs, err := filepath.Abs(value); err == nil {
+			os.Setenv(name, abs)
+		}
+	}
+}
+
+// stageAll 暂存所有更改
+//
+// 通过 GIT_WORK_TREE 指定工作区时当前目录可能不在工作区内，此时改用 -A 暂存整个工作区。
+func stageAll() {
+	if len(pathspecs) > 0 {
+		runGitCommand(withPathspecs("add", "-A")...)
+		return
+	}
+	if os.Getenv("GIT_WORK_TREE") != "" {
+		runGitCommand("add", "-A")
+		return
+	}
+	runGitCommand("add", ".")
+}
 
-	fmt.Println("Commit complete with message: ")
-	fmt.Println()
-	fmt.Println(commitMessage)
+// ensureGitRepository 检查当前目录是否在 Git 仓库中，不在时给出明确的错误提示
+func ensureGitRepository() {
+	if _, err := gitOutput("rev-parse", "--git-dir"); err == nil {
+		return
+	}
+
+	cwd, _ := os.Getwd()
+	fail("err.not_repo", cwd)
 }
 
 func parseArgs() cmdArgs {
@@ -121,15 +566,68 @@ func parseArgs() cmdArgs {
 		showHelp: false,
 	}
 
-	for _, arg := range os.Args[1:] {
-		if arg == "--help" || arg == "-h" {
+	for i, arg := range os.Args[1:] {
+		if arg == "--" {
+			args.paths = append(args.paths, os.Args[i+2:]...)
+			break
+		} else if !strings.HasPrefix(arg, "-") {
+			args.paths = append(args.paths, arg)
+		} else if arg == "--help" || arg == "-h" {
 			args.showHelp = true
+		} else if arg == "--edit" || arg == "-e" {
+			args.edit = true
+		} else if arg == "--interactive" || arg == "-i" {
+			args.interactive = true
+		} else if arg == "--copy" {
+			args.copy = true
+		} else if arg == "--no-commit" {
+			args.noCommit = true
+		} else if arg == "--allow-sensitive" {
+			args.allowSensitive = true
+		} else if arg == "--structural-diff" {
+			args.structuralDiff = true
+		} else if arg == "--breaking" {
+			args.breaking = true
+		} else if strings.HasPrefix(arg, "--breaking=") {
+			args.breaking = true
+			args.breakingNote = strings.TrimSpace(strings.TrimPrefix(arg, "--breaking="))
 		} else if strings.HasPrefix(arg, "--lang=") {
 			args.lang = strings.TrimPrefix(arg, "--lang=")
 		} else if strings.HasPrefix(arg, "--notes=") {
 			args.notes = strings.TrimPrefix(arg, "--notes=")
+		} else if strings.HasPrefix(arg, "--goal=") {
+			goal := strings.TrimPrefix(arg, "--goal=")
+			args.goal = &goal
+		} else if strings.HasPrefix(arg, "--model=") {
+			args.model = strings.TrimPrefix(arg, "--model=")
+		} else if strings.HasPrefix(arg, "--temperature=") {
+			value, err := strconv.ParseFloat(strings.TrimPrefix(arg, "--temperature="), 64)
+			if err != nil || value < 0 || value > 2 {
+				fail("arg.invalid_temperature", arg)
+			}
+			args.temperature = &value
+		} else if strings.HasPrefix(arg, "--max-tokens=") {
+			value, err := strconv.Atoi(strings.TrimPrefix(arg, "--max-tokens="))
+			if err != nil || value <= 0 {
+				fail("arg.invalid_max_tokens", arg)
+			}
+			args.maxTokens = value
+		} else if strings.HasPrefix(arg, "--max-parallel=") {
+			value, err := strconv.Atoi(strings.TrimPrefix(arg, "--max-parallel="))
+			if err != nil || value <= 0 {
+				fail("arg.invalid_max_parallel", arg)
+			}
+			args.maxParallel = value
+		} else if strings.HasPrefix(arg, "--deadline=") {
+			value, err := time.ParseDuration(strings.TrimPrefix(arg, "--deadline="))
+			if err != nil || value <= 0 {
+				fail("arg.invalid_deadline", arg)
+			}
+			args.deadline = value
+		} else if errorFormat == errorFormatJSON {
+			fail("arg.unknown", arg)
 		} else {
-			fmt.Printf("Unknown parameter passed: %s\n", arg)
+			fmt.Fprintln(stdout, tr("arg.unknown", arg))
 			args.showHelp = true
 		}
 	}
@@ -158,7 +656,7 @@ func getConfigFilePath() (string, error) {
 func createDefaultConfig(configPath string) error {
 	// 默认配置
 	defaultConfig := Config{
-		OpenAIEndpoint: "https://api.openai.com/v1/chat/completions",
+		OpenAIEndpoint: defaultEndpoint,
 		APIKey:         "",
 		DefaultLang:    "en",
 		ProxyURL:       "",
@@ -184,8 +682,7 @@ func createDefaultConfig(configPath string) error {
 		return err
 	}
 
-	fmt.Printf("默认配置文件已创建: %s\n", configPath)
-	fmt.Println("请编辑配置文件设置您的 OpenAI API 密钥")
+	fmt.Fprintln(stdout, tr("config.created", configPath))
 
 	return nil
 }
@@ -204,30 +701,106 @@ func loadConfig() error {
 		if err := createDefaultConfig(configPath); err != nil {
 			return err
 		}
-		// 配置文件已创建，但没有API密钥，提示用户编辑
-		os.Exit(0)
+		// 配置文件已创建，环境变量中也没有API密钥时，提示用户编辑
+		key, env := apiKeyFromEnv(defaultEndpoint)
+		if key == "" {
+			fmt.Fprintln(stdout, tr("config.edit_api_key"))
+			os.Exit(0)
+		}
+		fmt.Fprintln(stdout, tr("config.using_env_key", env))
+	}
+
+	if err := readConfig(configPath); err != nil {
+		return err
+	}
+
+	// 离线模式下服务商不在本机时不会发送请求，不需要密钥
+	if offlineFallback() {
+		return nil
+	}
+
+	// 使用网关登录时以访问令牌作为密钥，令牌过期时自动刷新
+	if config.APIKey == "" && config.AuthProvider != "" {
+		token, err := accessToken(config.AuthProvider)
+		if err != nil {
+			return err
+		}
+		config.APIKey = token
+	}
+
+	// 验证配置
+	if config.APIKey == "" {
+		fail("config.no_api_key", strings.Join(apiKeyEnvCandidates(config.OpenAIEndpoint), ", "), configPath)
+	}
+
+	return nil
+}
+
+// readConfigFile 读取配置文件，去掉 BOM 并将 UTF-16 转为 UTF-8
+//
+// Windows PowerShell 5.1 的 > 和 Out-File 默认写入 UTF-16，Set-Content 等会写入带 BOM 的 UTF-8，
+// encoding/json 无法直接解析这两种文件。
+func readConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:], nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		bigEndian := data[0] == 0xFE
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			if bigEndian {
+				units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+			} else {
+				units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+			}
+		}
+		return []byte(string(utf16.Decode(units))), nil
 	}
+	return data, nil
+}
 
+// readConfig 读取配置文件并填充默认值
+func readConfig(configPath string) error {
 	// 读取配置文件
-	jsonData, err := os.ReadFile(configPath)
+	jsonData, err := readConfigFile(configPath)
 	if err != nil {
 		return err
 	}
 
-	// 解析JSON
-	if err := json.Unmarshal(jsonData, &config); err != nil {
+	// 按配置结构校验并解析JSON
+	parsed, err := checkConfigFile(configPath, jsonData)
+	if err != nil {
 		return err
 	}
+	config = parsed
 
-	// 验证配置
-	if config.APIKey == "" {
-		fmt.Println("错误: 配置文件中未设置 API 密钥")
-		fmt.Printf("请编辑配置文件: %s\n", configPath)
-		os.Exit(1)
+	// 应用选择的服务商配置
+	profile, err := selectProfile()
+	if err != nil {
+		return err
+	}
+	if profile != nil {
+		applyProfile(profile)
+	}
+	if err := applyCommitAs(); err != nil {
+		return err
 	}
 
+	if config.OpenAIEndpoint == "" && len(config.OpenAIEndpoints) > 0 {
+		config.OpenAIEndpoint = config.OpenAIEndpoints[0]
+	}
 	if config.OpenAIEndpoint == "" {
-		config.OpenAIEndpoint = "https://api.openai.com/v1/chat/completions"
+		config.OpenAIEndpoint = defaultEndpoint
+	}
+
+	// 配置文件中没有密钥也没有使用网关登录时，使用环境变量中的密钥
+	if config.APIKey == "" && config.AuthProvider == "" {
+		config.APIKey, _ = apiKeyFromEnv(config.OpenAIEndpoint)
 	}
 
 	if config.DefaultLang == "" {
@@ -242,7 +815,8 @@ func loadConfig() error {
 		config.MaxTokens = 500
 	}
 
-	if config.Temperature <= 0 {
+	// 服务商配置中明确设置的温度（包括 0）不使用默认值
+	if config.Temperature <= 0 && (profile == nil || profile.Temperature == nil) {
 		config.Temperature = 0.7
 	}
 
@@ -250,126 +824,197 @@ func loadConfig() error {
 }
 
 func printHelp() {
-	fmt.Println("AI Commit - 使用 AI 生成 Git 提交信息的工具")
-	fmt.Println()
-	fmt.Println("用法:")
-	fmt.Println("  aicommit [选项]")
-	fmt.Println()
-	fmt.Println("选项:")
-	fmt.Println("  -h, --help     显示帮助信息")
-	fmt.Println("  --lang=<lang>  设置提交信息的语言 (默认从配置文件读取)")
-	fmt.Println("  --notes=<text> 添加额外备注")
-	fmt.Println()
-	fmt.Println("配置文件:")
-	fmt.Println("  ~/.aicommit/config.json")
-	fmt.Println()
-	fmt.Println("示例:")
-	fmt.Println("  aicommit")
-	fmt.Println("  aicommit --lang=zh")
-	fmt.Println("  aicommit --lang=zh --notes=紧急修复")
+	fmt.Fprint(stdout, tr("help"))
 }
 
 func runGitCommand(args ...string) string {
-	cmd := exec.Command("git", args...)
+	cmd := exec.Command("git", gitCompatArgs(args)...)
 	var output bytes.Buffer
 	cmd.Stdout = &output
-	cmd.Stderr = os.Stderr
+	cmd.Stderr = stderr
 
 	if err := cmd.Run(); err != nil {
-		fmt.Printf("Error running git command: %v\n", err)
-		os.Exit(1)
+		fail("err.git", err)
 	}
 
 	return output.String()
 }
 
+// gitOutput 运行 git 命令并返回输出，出错时返回错误而不退出
+func gitOutput(args ...string) (string, error) {
+	cmd := exec.Command("git", gitCompatArgs(args)...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	return output.String(), nil
+}
+
 func getGitDiff() string {
 	// 获取工作目录差异
-	workingDiff := runGitCommand("diff")
+	workingDiff := runGitCommand(withPathspecs("diff")...)
 	// 获取暂存区差异
-	stagedDiff := runGitCommand("diff", "--cached")
+	stagedDiff := runGitCommand(withPathspecs("diff", "--cached")...)
 
 	return workingDiff + stagedDiff
 }
 
-func generateCommitMessage(diff, lang, notes string) string {
-	// 构建请求体
-	reqBody := openAIRequest{
-		Model: config.Model,
-		Messages: []message{
-			{
-				Role:    "user",
-				Content: fmt.Sprintf("Analyze the following code changes and generate a concise Git commit message, providing it in the following languages: %s. Text only: \n\n%s\n\n %s \n\n", lang, diff, notes),
-			},
-		},
-		MaxTokens:   config.MaxTokens,
-		Temperature: config.Temperature,
-	}
+// getChangedFiles 获取工作目录和暂存区中有改动的文件
+func getChangedFiles() []string {
+	output := runGitCommand(withPathspecs("diff", "--name-only")...) + runGitCommand(withPathspecs("diff", "--cached", "--name-only")...)
 
-	// 编码为 JSON
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		fmt.Printf("Error marshalling JSON: %v\n", err)
-		os.Exit(1)
+	seen := make(map[string]bool)
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		file := strings.TrimSpace(line)
+		if file == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		files = append(files, file)
 	}
 
-	// 创建 HTTP 客户端
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	return files
+}
 
-	// 创建请求
-	req, err := http.NewRequest("POST", config.OpenAIEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		os.Exit(1)
-	}
+// promptContext 生成提交信息所需的上下文
+type promptContext struct {
+	diff        string
+	stat        string
+	lang        string
+	notes       string
+	files       []string
+	diffs       []fileDiff
+	newFiles    []string
+	condensed   []string
+	structural  []string
+	skipped     []string
+	generated   []string
+	vendored    []vendoredDir
+	restructure restructureInfo
+	sequencer   *sequencerState
+	avoid       []string
+	// breaking 使用 --breaking 标记为破坏性变更，breakingNote 为说明
+	breaking     bool
+	breakingNote string
+	// goal 当天的工作目标，见 daily_context
+	goal       string
+	summarized bool
+}
 
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+config.APIKey)
+func generateCommitMessage(ctx promptContext) string {
+	var recent []string
+	if n := dedupHistory(); n > 0 {
+		recent = getRecentSubjects(n)
+	}
+
+	var message string
+	for attempt := 0; ; attempt++ {
+		result, err := requestPrompt(buildPrompt(ctx))
+		content := result.content
+		if errors.Is(err, errDeadlineExceeded) {
+			// 重新生成时超时则沿用上一次的结果
+			if message == "" {
+				message = deadlineFallback(ctx, content)
+			}
+			break
+		}
+		if err != nil {
+			failWith(err)
+		}
+		message = content
+		if cached := result.usage.cachedTokens(); cached > 0 {
+			fmt.Fprintln(stdout, tr("commit.cache_hit", cached, result.usage.PromptTokens))
+		}
 
-	// 发送请求
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Error calling OpenAI API: %v\n", err)
-		os.Exit(1)
-	}
-	defer resp.Body.Close()
+		// 去除可能的引号
+		message = strings.TrimPrefix(message, `"`)
+		message = strings.TrimSuffix(message, `"`)
+		message = strings.TrimSpace(message)
 
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("Error reading response: %v\n", err)
-		os.Exit(1)
+		// 标题与最近的提交重复时重新生成
+		if recent == nil || message == "" || attempt >= maxDedupRetries {
+			break
+		}
+		duplicate, found := findDuplicateSubject(messageSubject(message), recent)
+		if !found {
+			break
+		}
+		fmt.Fprintln(stdout, tr("commit.duplicate", duplicate))
+		ctx.avoid = appendUnique(ctx.avoid, messageSubject(message), duplicate)
 	}
 
-	// 解析响应
-	var openAIResp openAIResponse
-	if err := json.Unmarshal(respBody, &openAIResp); err != nil {
-		fmt.Printf("Error unmarshalling response: %v\n", err)
-		os.Exit(1)
-	}
+	return message
+}
 
-	// 检查错误
-	if openAIResp.Error != nil {
-		fmt.Printf("Error from OpenAI API: %s\n", openAIResp.Error.Message)
-		os.Exit(1)
+// buildPrompt 构建生成提交信息的提示词，开启 prompt_cache 时把不变的部分拆到前缀中
+func buildPrompt(ctx promptContext) chatPrompt {
+	if promptCacheMode() != promptCacheOff {
+		return buildCachedPrompt(ctx)
 	}
 
-	// 返回生成的提交信息
-	if len(openAIResp.Choices) > 0 {
-		message := openAIResp.Choices[0].Message.Content
-		// 去除可能的引号
-		message = strings.TrimPrefix(message, `"`)
-		message = strings.TrimSuffix(message, `"`)
-		return strings.TrimSpace(message)
+	return chatPrompt{text: renderTemplate("commit", newPromptData(ctx))}
+}
+
+// promptHints 根据上下文生成附加提示
+func promptHints(ctx promptContext) []string {
+	var hints []string
+
+	if hint := scopeHint(ctx.files); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := sequencerHint(ctx.sequencer); hint != "" {
+		hints = append(hints, hint)
+	} else if hint := commitTypeHint(ctx.files); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := languagesHint(ctx.diffs); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := newFilesHint(ctx.newFiles); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := condensedFilesHint(ctx.condensed); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := skippedFilesHint(ctx.skipped); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := generatedFilesHint(ctx.generated); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := vendoredDirsHint(ctx.vendored); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := structuralFilesHint(ctx.structural); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := breakingHint(ctx.breaking, ctx.breakingNote); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := dailyGoalHint(ctx.goal); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := restructureHint(ctx.restructure); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := testPlanHint(ctx.diffs); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := summarizedHint(ctx.summarized); hint != "" {
+		hints = append(hints, hint)
+	}
+	if hint := dedupHint(ctx.avoid); hint != "" {
+		hints = append(hints, hint)
 	}
 
-	return ""
+	return hints
 }
 
 func commitChanges(message string) {
 	// 提交更改
-	runGitCommand("commit", "-m", message)
+	runGitCommand(withPathspecs(withAuthor("commit", "-m", message)...)...)
 }
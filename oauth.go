@@ -0,0 +1,404 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// tokensFileName 登录后保存令牌的文件，与配置文件在同一目录
+	tokensFileName = "tokens.json"
+
+	deviceCodeGrant   = "urn:ietf:params:oauth:grant-type:device_code"
+	refreshTokenGrant = "refresh_token"
+
+	// 轮询令牌接口的默认间隔，以及收到 slow_down 时增加的间隔 (RFC 8628)
+	defaultDevicePollInterval = 5
+	slowDownIncrement         = 5
+
+	// tokenExpiryMargin 令牌在到期前多久视为过期，避免请求途中失效
+	tokenExpiryMargin = time.Minute
+)
+
+// AuthProvider 使用 OAuth 2.0 设备授权 (RFC 8628) 登录的网关
+//
+// 团队使用带单点登录的托管网关时，通过 aicommit auth login 在浏览器中登录，
+// 得到的短期令牌代替长期有效的静态 API 密钥，过期后用刷新令牌自动续期。
+type AuthProvider struct {
+	DeviceAuthorizationURL string `json:"device_authorization_url"`
+	TokenURL               string `json:"token_url"`
+	ClientID               string `json:"client_id"`
+	Scope                  string `json:"scope,omitempty"`
+	Audience               string `json:"audience,omitempty"`
+}
+
+// oauthToken 保存的令牌
+type oauthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"`
+}
+
+// tokenResponse 令牌接口的响应，出错时只有 error 和 error_description
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// deviceAuthorization 设备授权接口的响应
+type deviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+func runAuth(args []string) {
+	if len(args) == 0 || args[0] == "--help" || args[0] == "-h" {
+		printHelp()
+		os.Exit(0)
+	}
+
+	command := args[0]
+	provider := ""
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if value, ok := flagValue(args, &i, "--provider"); ok {
+			provider = value
+		} else {
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	// 登录前还没有 API 密钥，只读取已有的配置文件
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		fail("err.load_config", err)
+	}
+	if _, err := os.Stat(configPath); err == nil {
+		if err := readConfig(configPath); err != nil {
+			fail("err.load_config", err)
+		}
+	}
+
+	switch command {
+	case "login":
+		name, p := authProviderFor(provider)
+		if err := deviceLogin(name, p); err != nil {
+			failWith(err)
+		}
+	case "logout":
+		name, _ := authProviderFor(provider)
+		tokens := loadTokens()
+		delete(tokens, name)
+		if err := saveTokens(tokens); err != nil {
+			fail("auth.save_failed", err)
+		}
+		fmt.Fprintln(stdout, tr("auth.logged_out", name))
+	case "status":
+		printAuthStatus()
+	default:
+		failUsage("arg.unknown", command)
+	}
+}
+
+// authProviderFor 返回指定的网关，未指定时使用 auth_provider 或唯一配置的网关
+func authProviderFor(name string) (string, AuthProvider) {
+	if name == "" {
+		name = config.AuthProvider
+	}
+	if name == "" && len(config.AuthProviders) == 1 {
+		for n := range config.AuthProviders {
+			name = n
+		}
+	}
+	if name == "" {
+		fail("auth.provider_required", strings.Join(authProviderNames(), ", "))
+	}
+	p, ok := config.AuthProviders[name]
+	if !ok {
+		fail("auth.unknown_provider", name, strings.Join(authProviderNames(), ", "))
+	}
+	return name, p
+}
+
+// authProviderNames 返回配置的网关名称
+func authProviderNames() []string {
+	names := make([]string, 0, len(config.AuthProviders))
+	for name := range config.AuthProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// deviceLogin 进行设备授权：显示验证地址和用户码，轮询令牌接口直到用户在浏览器中完成登录
+func deviceLogin(name string, p AuthProvider) error {
+	form := url.Values{"client_id": {p.ClientID}}
+	if p.Scope != "" {
+		form.Set("scope", p.Scope)
+	}
+	if p.Audience != "" {
+		form.Set("audience", p.Audience)
+	}
+
+	var auth deviceAuthorization
+	if status, err := postOAuthForm(p.DeviceAuthorizationURL, form, &auth); err != nil {
+		return newError("auth.request_failed", p.DeviceAuthorizationURL, err)
+	} else if status != http.StatusOK || auth.DeviceCode == "" {
+		return newError("auth.request_failed", p.DeviceAuthorizationURL, fmt.Sprintf("HTTP %d", status))
+	}
+
+	if auth.VerificationURIComplete != "" {
+		fmt.Fprintln(stdout, tr("auth.open_complete", auth.VerificationURIComplete, auth.UserCode))
+	} else {
+		fmt.Fprintln(stdout, tr("auth.open", auth.VerificationURI, auth.UserCode))
+	}
+
+	interval := auth.Interval
+	if interval <= 0 {
+		interval = defaultDevicePollInterval
+	}
+	deadline := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	for auth.ExpiresIn <= 0 || time.Now().Before(deadline) {
+		time.Sleep(time.Duration(interval) * time.Second)
+
+		var resp tokenResponse
+		_, err := postOAuthForm(p.TokenURL, url.Values{
+			"grant_type":  {deviceCodeGrant},
+			"device_code": {auth.DeviceCode},
+			"client_id":   {p.ClientID},
+		}, &resp)
+		if err != nil {
+			return newError("auth.request_failed", p.TokenURL, err)
+		}
+
+		switch resp.Error {
+		case "":
+			if resp.AccessToken == "" {
+				return newError("auth.request_failed", p.TokenURL, "no access_token")
+			}
+			if err := storeToken(name, resp, ""); err != nil {
+				return newError("auth.save_failed", err)
+			}
+			fmt.Fprintln(stdout, tr("auth.logged_in", name))
+			return nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += slowDownIncrement
+		case "access_denied":
+			return newError("auth.denied")
+		case "expired_token":
+			return newError("auth.expired")
+		default:
+			return newError("auth.request_failed", p.TokenURL, oauthErrorText(resp))
+		}
+	}
+	return newError("auth.expired")
+}
+
+// accessToken 返回网关的访问令牌，即将过期时用刷新令牌续期
+func accessToken(name string) (string, error) {
+	p, ok := config.AuthProviders[name]
+	if !ok {
+		return "", newError("auth.unknown_provider", name, strings.Join(authProviderNames(), ", "))
+	}
+	token, ok := loadTokens()[name]
+	if !ok || token.AccessToken == "" {
+		return "", newError("auth.login_required", name)
+	}
+	if token.ExpiresAt == 0 || time.Now().Add(tokenExpiryMargin).Unix() < token.ExpiresAt {
+		return token.AccessToken, nil
+	}
+	if token.RefreshToken == "" {
+		return "", newError("auth.login_required", name)
+	}
+
+	var resp tokenResponse
+	status, err := postOAuthForm(p.TokenURL, url.Values{
+		"grant_type":    {refreshTokenGrant},
+		"refresh_token": {token.RefreshToken},
+		"client_id":     {p.ClientID},
+	}, &resp)
+	if err != nil {
+		return "", newRetryableError("auth.request_failed", p.TokenURL, err)
+	}
+	if resp.Error != "" || resp.AccessToken == "" {
+		// 刷新令牌失效或被吊销，需要重新登录
+		if status >= 500 {
+			return "", newRetryableError("auth.request_failed", p.TokenURL, fmt.Sprintf("HTTP %d", status))
+		}
+		return "", newError("auth.login_required", name)
+	}
+	if err := storeToken(name, resp, token.RefreshToken); err != nil {
+		return "", newError("auth.save_failed", err)
+	}
+	return resp.AccessToken, nil
+}
+
+// storeToken 保存令牌，响应中没有新的刷新令牌时沿用原来的
+func storeToken(name string, resp tokenResponse, refreshToken string) error {
+	token := oauthToken{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	if resp.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Unix() + resp.ExpiresIn
+	}
+
+	tokens := loadTokens()
+	tokens[name] = token
+	return saveTokens(tokens)
+}
+
+// postOAuthForm 以表单提交 OAuth 请求并解析 JSON 响应，返回 HTTP 状态码
+//
+// OAuth 的错误 (如 authorization_pending) 以 HTTP 400 返回，由调用方根据 error 字段处理。
+func postOAuthForm(endpoint string, form url.Values, v interface{}) (int, error) {
+	client, err := newHTTPClient(requestTimeout())
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.StatusCode, nil
+}
+
+// oauthErrorText 返回 OAuth 错误的说明
+func oauthErrorText(resp tokenResponse) string {
+	if resp.ErrorDescription != "" {
+		return resp.Error + ": " + resp.ErrorDescription
+	}
+	return resp.Error
+}
+
+// tokensPath 返回令牌文件的路径
+func tokensPath() (string, error) {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), tokensFileName), nil
+}
+
+// loadTokens 读取保存的令牌，文件不存在或损坏时返回空
+func loadTokens() map[string]oauthToken {
+	tokens := map[string]oauthToken{}
+	if path, err := tokensPath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &tokens)
+		}
+	}
+	if tokens == nil {
+		tokens = map[string]oauthToken{}
+	}
+	return tokens
+}
+
+// saveTokens 保存令牌，文件只允许当前用户读写
+func saveTokens(tokens map[string]oauthToken) error {
+	path, err := tokensPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// printAuthStatus 列出配置的网关及其登录状态
+func printAuthStatus() {
+	names := authProviderNames()
+	if len(names) == 0 {
+		fmt.Fprintln(stdout, tr("auth.no_providers"))
+		return
+	}
+
+	tokens := loadTokens()
+	for _, name := range names {
+		token, ok := tokens[name]
+		switch {
+		case !ok || token.AccessToken == "":
+			fmt.Fprintln(stdout, tr("auth.status_logged_out", name))
+		case token.ExpiresAt == 0:
+			fmt.Fprintln(stdout, tr("auth.status_logged_in", name))
+		case time.Now().Unix() >= token.ExpiresAt && token.RefreshToken == "":
+			fmt.Fprintln(stdout, tr("auth.status_expired", name))
+		default:
+			fmt.Fprintln(stdout, tr("auth.status_expires", name, time.Unix(token.ExpiresAt, 0).Format("2006-01-02 15:04"), token.RefreshToken != ""))
+		}
+	}
+}
+
+// validateAuthProviders 校验 auth_providers 和 auth_provider
+func validateAuthProviders(c *Config, lines map[string]int) []configIssue {
+	var issues []configIssue
+	add := func(key, message string) {
+		issues = append(issues, configIssue{line: lines[key], key: key, message: message})
+	}
+
+	for name, p := range c.AuthProviders {
+		prefix := "auth_providers." + name + "."
+		if !isHTTPURL(p.DeviceAuthorizationURL) {
+			add(prefix+"device_authorization_url", tr("schema.invalid_url"))
+		}
+		if !isHTTPURL(p.TokenURL) {
+			add(prefix+"token_url", tr("schema.invalid_url"))
+		}
+		if p.ClientID == "" {
+			add(prefix+"client_id", tr("schema.empty_entry"))
+		}
+	}
+	if _, ok := c.AuthProviders[c.AuthProvider]; c.AuthProvider != "" && !ok {
+		add("auth_provider", tr("schema.unknown_auth_provider", c.AuthProvider))
+	}
+	for name, p := range c.Profiles {
+		if _, ok := c.AuthProviders[p.AuthProvider]; p.AuthProvider != "" && !ok {
+			add("profiles."+name+".auth_provider", tr("schema.unknown_auth_provider", p.AuthProvider))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// offlineEnv 离线模式下传给插件的环境变量
+const offlineEnv = "AICOMMIT_OFFLINE"
+
+// offlineFlag 通过 --offline 开启离线模式
+var offlineFlag bool
+
+// offlineMode 是否处于离线模式 (--offline、配置中的 offline 或 AICOMMIT_OFFLINE 环境变量)
+//
+// 环境变量会传给插件，插件再调用 aicommit 时同样处于离线模式。
+func offlineMode() bool {
+	return offlineFlag || config.Offline || os.Getenv(offlineEnv) != ""
+}
+
+// loadOfflineSetting 运行插件前只读取配置文件中的 offline，不做完整的加载和校验
+//
+// 插件不一定需要模型，配置不完整（如还没有密钥）时也应该可以运行，但配置中开启的
+// 离线模式仍要通过 AICOMMIT_OFFLINE 传给插件。
+func loadOfflineSetting() {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return
+	}
+	data, err := readConfigFile(configPath)
+	if err != nil {
+		return
+	}
+	var c struct {
+		Offline bool `json:"offline"`
+	}
+	if json.Unmarshal(data, &c) == nil && c.Offline {
+		config.Offline = true
+	}
+}
+
+// isLoopbackHost 判断主机名是否指向本机，不做 DNS 解析
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// localProvider 判断配置的所有端点是否都在本机，如 Ollama 或 llama.cpp 的本地服务
+func localProvider() bool {
+	for _, endpoint := range configuredEndpoints() {
+		u, err := url.Parse(endpoint)
+		if err != nil || !isLoopbackHost(u.Hostname()) {
+			return false
+		}
+	}
+	return true
+}
+
+// offlineFallback 离线模式下服务商不在本机时，提交信息改为在本地根据改动文件生成
+func offlineFallback() bool {
+	return offlineMode() && !localProvider()
+}
+
+// offlineDialContext 离线模式下只允许连接本机地址，其他连接直接报错退出
+//
+// 所有 HTTP 客户端都通过 newHTTPClient 创建，在拨号这一层拦截可以覆盖代理、重定向、
+// 令牌刷新等所有途径，新增的联网代码也无法绕过。localhost 不经过 DNS，直接连接回环地址。
+func offlineDialContext(dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || !isLoopbackHost(host) {
+			fail("offline.blocked", address)
+		}
+		if net.ParseIP(host) != nil {
+			return dial(ctx, network, address)
+		}
+
+		conn, err := dial(ctx, network, net.JoinHostPort("127.0.0.1", port))
+		if err != nil {
+			if conn6, err6 := dial(ctx, network, net.JoinHostPort("::1", port)); err6 == nil {
+				return conn6, nil
+			}
+		}
+		return conn, err
+	}
+}
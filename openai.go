@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type openAIRequest struct {
+	Model               string         `json:"model"`
+	Messages            []message      `json:"messages"`
+	MaxTokens           int            `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int            `json:"max_completion_tokens,omitempty"`
+	Temperature         *float64       `json:"temperature,omitempty"`
+	TopP                *float64       `json:"top_p,omitempty"`
+	Stream              bool           `json:"stream,omitempty"`
+	StreamOptions       *streamOptions `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type message struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+
+	// 命中提示词缓存的令牌数，OpenAI 格式在 prompt_tokens_details 中，Anthropic 格式为 cache_read_input_tokens
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens"`
+}
+
+// cachedTokens 返回命中缓存的令牌数
+func (u usage) cachedTokens() int {
+	if u.PromptTokensDetails.CachedTokens > 0 {
+		return u.PromptTokensDetails.CachedTokens
+	}
+	return u.CacheReadInputTokens
+}
+
+// completion 一次 API 调用的结果
+type completion struct {
+	content string
+	usage   usage
+}
+
+// requestCompletion 调用 OpenAI API 并返回生成的文本
+func requestCompletion(prompt string) (string, error) {
+	result, err := requestChat(prompt)
+	return result.content, err
+}
+
+// chatSettings 单次请求使用的模型参数
+type chatSettings struct {
+	model       string
+	temperature float64
+}
+
+// currentChatSettings 返回配置中的模型参数
+func currentChatSettings() chatSettings {
+	return chatSettings{model: config.Model, temperature: config.Temperature}
+}
+
+// requestChat 调用 OpenAI API 并返回生成的文本和令牌用量
+func requestChat(prompt string) (completion, error) {
+	return requestPromptWith(chatPrompt{text: prompt}, currentChatSettings())
+}
+
+// requestPrompt 发送分为前缀和正文的提示词
+func requestPrompt(prompt chatPrompt) (completion, error) {
+	return requestPromptWith(prompt, currentChatSettings())
+}
+
+// requestPromptWith 使用指定的模型参数调用 OpenAI API，可以并发调用
+func requestPromptWith(prompt chatPrompt, settings chatSettings) (completion, error) {
+	caps := providerCapabilities(settings.model)
+
+	// 设置了截止时间时使用流式响应，超时后仍能拿到已生成的部分内容
+	stream := !generationDeadline.IsZero() && supports(caps.Stream)
+
+	// 构建请求体
+	temperature := settings.temperature
+	reqBody := openAIRequest{
+		Model:       settings.model,
+		Messages:    chatMessages(prompt),
+		MaxTokens:   config.MaxTokens,
+		Temperature: &temperature,
+		TopP:        config.TopP,
+	}
+	applyCapabilities(&reqBody, caps)
+	if stream {
+		reqBody.Stream = true
+		reqBody.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	// 编码为 JSON
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return completion{}, newError("api.marshal", err)
+	}
+
+	// 创建 HTTP 客户端
+	client, err := newHTTPClient(requestTimeout())
+	if err != nil {
+		return completion{}, newError("api.client", err)
+	}
+	// 守护进程不转发流式响应
+	if !stream {
+		useDaemon(client, requestTimeout())
+	}
+
+	// --deadline 对所有请求生效，不支持流式响应时到期直接取消，改用本地推断的标题
+	ctx := context.Background()
+	if !generationDeadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, generationDeadline)
+		defer cancel()
+	}
+
+	// 发送请求，网络错误、限流和服务端错误时按配置重试；配置了多个端点时先换用其他端点，不计入重试次数
+	var resp *http.Response
+	tried := map[string]bool{}
+	endpoint := selectEndpoint(tried)
+	for attempt := 0; ; attempt++ {
+		// 拒绝向不受信任的端点发送代码
+		if err := checkEndpointAllowed(endpoint); err != nil {
+			return completion{}, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
+		if err != nil {
+			return completion{}, newError("api.request", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+config.APIKey)
+
+		start := time.Now()
+		resp, err = client.Do(req)
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return completion{}, errDeadlineExceeded
+		}
+		retryable := err != nil || isRetryableStatus(resp.StatusCode)
+		if !retryable {
+			recordEndpointSuccess(endpoint, time.Since(start))
+			break
+		}
+
+		recordEndpointFailure(endpoint)
+		tried[endpoint] = true
+		if next := selectEndpoint(tried); next != "" {
+			if resp != nil {
+				resp.Body.Close()
+				fmt.Fprintln(stderr, tr("api.failover", endpoint, fmt.Sprintf("HTTP %d", resp.StatusCode), next))
+			} else {
+				fmt.Fprintln(stderr, tr("api.failover", endpoint, err, next))
+			}
+			endpoint = next
+			attempt--
+			continue
+		}
+		if attempt >= config.Retries {
+			if err != nil {
+				return completion{}, newRetryableError("api.call", err)
+			}
+			break
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		tried = map[string]bool{}
+		endpoint = selectEndpoint(tried)
+		delay := retryDelay(attempt)
+		if err != nil {
+			fmt.Fprintln(stderr, tr("api.retry_error", err, delay, attempt+1, config.Retries))
+		} else {
+			fmt.Fprintln(stderr, tr("api.retry_status", resp.StatusCode, delay, attempt+1, config.Retries))
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return completion{}, errDeadlineExceeded
+		}
+	}
+	defer resp.Body.Close()
+
+	// 不支持流式响应的服务会直接返回完整的 JSON
+	if stream && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readChatStream(ctx, resp.Body)
+	}
+
+	// 读取响应
+	respBody, err := io.ReadAll(resp.Body)
+	if ctx.Err() != nil {
+		return completion{}, errDeadlineExceeded
+	}
+	if err != nil {
+		return completion{}, newRetryableError("api.read", err)
+	}
+
+	// 解析响应并返回生成的文本
+	return parseChatResponse(respBody, resp.StatusCode)
+}
+
+// readChatStream 读取流式响应，截止时间到达时返回已接收的部分内容和 errDeadlineExceeded
+func readChatStream(ctx context.Context, body io.Reader) (completion, error) {
+	var result completion
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		delta, chunkUsage, err := parseChatChunk(data)
+		if err != nil {
+			return completion{}, err
+		}
+		if chunkUsage != nil {
+			result.usage = *chunkUsage
+		}
+		content.WriteString(delta)
+	}
+
+	// 部分内容保留末尾的换行，用于判断标题是否已经完整
+	if ctx.Err() != nil {
+		result.content = strings.TrimLeft(normalizeNewlines(content.String()), " \t\n")
+		return result, errDeadlineExceeded
+	}
+	result.content = strings.TrimSpace(normalizeNewlines(content.String()))
+	if err := scanner.Err(); err != nil {
+		return completion{}, newRetryableError("api.read", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	pathPatternCache   = make(map[string]*regexp.Regexp)
+	pathPatternCacheMu sync.Mutex
+)
+
+// matchPathPattern 判断路径是否匹配 gitignore 风格的模式
+//
+// 支持 `*`、`?`、`**`，以 `/` 开头或中间包含 `/` 的模式相对仓库根目录锚定，
+// 以 `/` 结尾的模式只匹配目录。匹配目录的模式同时匹配目录下的所有文件。
+func matchPathPattern(pattern, path string) bool {
+	re := compilePathPattern(pattern)
+	return re != nil && re.MatchString(path)
+}
+
+func compilePathPattern(pattern string) *regexp.Regexp {
+	pathPatternCacheMu.Lock()
+	defer pathPatternCacheMu.Unlock()
+
+	if re, ok := pathPatternCache[pattern]; ok {
+		return re
+	}
+
+	p := strings.TrimSpace(pattern)
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		pathPatternCache[pattern] = nil
+		return nil
+	}
+
+	var b strings.Builder
+	skip := 0
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("(?:^|/)")
+	}
+	// 按字符而不是字节处理，非 ASCII 的模式（如 文档/*.md）才能正确匹配
+	for i, c := range p {
+		switch {
+		case i < skip:
+			continue
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			skip = i + 3
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			skip = i + 2
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		re = nil
+	}
+	pathPatternCache[pattern] = re
+	return re
+}
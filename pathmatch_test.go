@@ -0,0 +1,92 @@
+package main
+
+import "testing"
+
+func TestMatchPathPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		// 不含 / 的模式匹配任意层级
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/app/main.go", true},
+		{"*.go", "main.gox", false},
+		{"vendor", "vendor/lib/a.go", true},
+		{"vendor", "src/vendor/a.go", true},
+		// 含 / 的模式相对仓库根目录锚定
+		{"/docs", "docs/index.md", true},
+		{"/docs", "src/docs/index.md", false},
+		{"services/payments/", "services/payments/api.go", true},
+		{"services/payments/", "legacy/services/payments/api.go", false},
+		{"services/payments/", "services/payments-v2/api.go", false},
+		// 以 / 结尾的模式只匹配目录
+		{"build/", "build", false},
+		{"build/", "build/out.txt", true},
+		// * 和 ? 不跨目录
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/pkg/main.go", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		// ** 匹配任意层级
+		{"**/testdata", "testdata/a.json", true},
+		{"**/testdata", "pkg/x/testdata/a.json", true},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/**", "src/a/b", true},
+		// 非 ASCII 字符
+		{"文档/*.md", "文档/a.md", true},
+		{"文档/", "文档/指南/安装.md", true},
+		{"*.配置", "设置/本地.配置", true},
+		{"数据?.csv", "数据一.csv", true},
+		{"数据?.csv", "数据.csv", false},
+		{"文档/*.md", "文件/a.md", false},
+		// 正则元字符按字面匹配
+		{"a+b.txt", "a+b.txt", true},
+		{"a+b.txt", "aab.txt", false},
+		{"", "main.go", false},
+		{"/", "main.go", false},
+	}
+	for _, tt := range tests {
+		if got := matchPathPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchPathPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestScopeFromMap(t *testing.T) {
+	saved := config.ScopeMap
+	defer func() { config.ScopeMap = saved }()
+
+	config.ScopeMap = map[string]string{
+		"services/":          "services",
+		"services/payments/": "payments",
+		"services/billing/":  "billing",
+		"*.md":               "docs",
+		"*.go":               "golang",
+	}
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"services/payments/api.go", "payments"},
+		{"services/billing/invoice.go", "billing"},
+		{"services/auth/login.go", "services"},
+		{"README.md", "docs"},
+		{"cmd/main.go", "golang"},
+		{"Makefile", ""},
+	}
+	for _, tt := range tests {
+		if got := scopeFromMap(tt.file); got != tt.want {
+			t.Errorf("scopeFromMap(%q) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+
+	// 长度相同的模式按字典序选择，多次运行结果一致
+	config.ScopeMap = map[string]string{"docs/a*md": "a", "docs/*.md": "b", "*/api.md": "c"}
+	for i := 0; i < 20; i++ {
+		if got := scopeFromMap("docs/api.md"); got != "b" {
+			t.Fatalf("scopeFromMap tie = %q, want %q", got, "b")
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+// pathspecs 命令行指定的路径，为空时处理所有更改
+//
+// 与 git commit <paths> 相同，指定路径时只暂存、描述和提交这些路径的改动，
+// 暂存区中其他路径的改动保持不变。
+var pathspecs []string
+
+// withPathspecs 在 git 参数末尾追加 -- <pathspec>...
+func withPathspecs(args ...string) []string {
+	if len(pathspecs) == 0 {
+		return args
+	}
+	return append(append(args, "--"), pathspecs...)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+)
+
+// plainEnv 设置后使用无障碍的纯文本输出，插件可以通过它得知当前模式
+const plainEnv = "AICOMMIT_PLAIN"
+
+// plainOutput 是否使用纯文本输出：不使用颜色、分页器、终端转义序列和装饰性符号，
+// 输出逐行追加，适合屏幕阅读器和 dumb 终端
+var plainOutput bool
+
+// initPlainOutput 根据 AICOMMIT_PLAIN 和 TERM=dumb 决定是否默认使用纯文本输出
+func initPlainOutput() {
+	if os.Getenv(plainEnv) != "" || os.Getenv("TERM") == "dumb" {
+		enablePlainOutput()
+	}
+}
+
+// enablePlainOutput 开启纯文本输出，并让 git 子进程也不输出颜色、不使用分页器
+func enablePlainOutput() {
+	plainOutput = true
+	os.Setenv(plainEnv, "1")
+	os.Setenv("GIT_PAGER", "cat")
+	addGitConfig("color.ui", "false")
+}
+
+// supportsANSI 判断是否可以向文件输出终端转义序列
+func supportsANSI(f *os.File) bool {
+	return !plainOutput && isTerminal(f) && enableVirtualTerminal(f)
+}
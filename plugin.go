@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// pluginPrefix 插件可执行文件名前缀，与 git 的 git-<name> 约定相同
+const pluginPrefix = "aicommit-"
+
+// findPlugin 在 PATH 中查找子命令对应的插件
+//
+// 不带子命令时参数是限定提交范围的路径，当前目录中存在同名文件或目录、或参数是
+// *.go 这样的通配模式时按路径处理，避免 `aicommit docs` 碰巧运行了 PATH 中的 aicommit-docs。
+func findPlugin(name string) (string, bool) {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\*?[`) {
+		return "", false
+	}
+	if _, err := os.Lstat(name); err == nil {
+		return "", false
+	}
+
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err == nil {
+		return path, true
+	}
+
+	// PATHEXT 通常不包含 .ps1，Windows 中单独查找 PowerShell 脚本
+	if runtime.GOOS == "windows" {
+		for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+			script := filepath.Join(dir, pluginPrefix+name+".ps1")
+			if info, err := os.Stat(script); err == nil && !info.IsDir() {
+				return script, true
+			}
+		}
+	}
+	return "", false
+}
+
+// pluginCommand 构建运行插件的命令，PowerShell 脚本通过 pwsh 或 powershell 运行
+func pluginCommand(path string, args []string) *exec.Cmd {
+	if !strings.EqualFold(filepath.Ext(path), ".ps1") {
+		return exec.Command(path, args...)
+	}
+
+	shell := "powershell"
+	if _, err := exec.LookPath("pwsh"); err == nil {
+		shell = "pwsh"
+	}
+	return exec.Command(shell, append([]string{"-NoProfile", "-ExecutionPolicy", "Bypass", "-File", path}, args...)...)
+}
+
+// pluginEnv 构建传给插件的环境变量，包含配置路径和仓库信息
+func pluginEnv() []string {
+	env := os.Environ()
+
+	if configPath, err := getConfigFilePath(); err == nil {
+		env = append(env, "AICOMMIT_CONFIG="+configPath)
+	}
+	env = append(env, "AICOMMIT_UI_LANG="+uiLang)
+	if offlineMode() {
+		env = append(env, offlineEnv+"=1")
+	}
+
+	if root, err := gitOutput("rev-parse", "--show-toplevel"); err == nil {
+		env = append(env, "AICOMMIT_REPO_ROOT="+strings.TrimSpace(root))
+	}
+	if dir, err := gitDir(); err == nil {
+		env = append(env, "AICOMMIT_GIT_DIR="+dir)
+	}
+
+	return env
+}
+
+// runPlugin 运行插件并以插件的退出码退出
+func runPlugin(path string, args []string) {
+	cmd := pluginCommand(path, args)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = pluginEnv()
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		fail("plugin.failed", filepath.Base(path), err)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultGitHubGraphQL = "https://api.github.com/graphql"
+
+// githubRemotePattern 从远程仓库地址中解析 owner/name
+var githubRemotePattern = regexp.MustCompile(`[:/]([^/:]+)/([^/]+?)(?:\.git)?/?$`)
+
+// reviewThreadsQuery 查询拉取请求的评审讨论和要求修改的评审
+const reviewThreadsQuery = `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      title
+      url
+      reviewThreads(first: 100) {
+        nodes {
+          isResolved
+          isOutdated
+          path
+          line
+          comments(first: 50) {
+            nodes { author { login } body }
+          }
+        }
+      }
+      reviews(last: 50, states: CHANGES_REQUESTED) {
+        nodes { author { login } body }
+      }
+    }
+  }
+}`
+
+// reviewComment 评审评论
+type reviewComment struct {
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Body string `json:"body"`
+}
+
+// reviewThread 评审讨论
+type reviewThread struct {
+	IsResolved bool   `json:"isResolved"`
+	IsOutdated bool   `json:"isOutdated"`
+	Path       string `json:"path"`
+	Line       int    `json:"line"`
+	Comments   struct {
+		Nodes []reviewComment `json:"nodes"`
+	} `json:"comments"`
+}
+
+// pullRequestReview 拉取请求及其评审
+type pullRequestReview struct {
+	Title         string `json:"title"`
+	URL           string `json:"url"`
+	ReviewThreads struct {
+		Nodes []reviewThread `json:"nodes"`
+	} `json:"reviewThreads"`
+	Reviews struct {
+		Nodes []reviewComment `json:"nodes"`
+	} `json:"reviews"`
+}
+
+func runPRComments(args []string) {
+	number := 0
+	commit := false
+	repo := ""
+	lang := ""
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if arg == "--commit" {
+			commit = true
+		} else if value, ok := flagValue(args, &i, "--repo"); ok {
+			repo = value
+		} else if value, ok := flagValue(args, &i, "--lang"); ok {
+			lang = value
+		} else if n, err := strconv.Atoi(strings.TrimPrefix(arg, "#")); err == nil && n > 0 && number == 0 {
+			number = n
+		} else {
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	if number == 0 {
+		fail("pr.no_number")
+	}
+
+	ensureGitRepository()
+
+	if err := loadConfig(); err != nil {
+		fail("err.load_config", err)
+	}
+	if lang == "" {
+		lang = config.DefaultLang
+	}
+
+	owner, name, err := githubRepository(repo)
+	if err != nil {
+		failWith(err)
+	}
+
+	fmt.Fprintln(stdout, tr("pr.fetching", owner, name, number))
+	pr, err := fetchPullRequestReview(owner, name, number)
+	if err != nil {
+		failWith(err)
+	}
+
+	threads := outstandingThreads(pr)
+	if len(threads) == 0 && len(changeRequests(pr)) == 0 {
+		fmt.Fprintln(stdout, tr("pr.none", pr.URL))
+		os.Exit(0)
+	}
+	fmt.Fprintln(stdout, tr("pr.summarizing", len(threads)))
+
+	summary, err := requestCompletion(buildReviewSummaryPrompt(pr, threads, lang))
+	if err != nil {
+		failWith(err)
+	}
+	if summary == "" {
+		fail("pr.unable")
+	}
+
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, summary)
+
+	if !commit {
+		return
+	}
+
+	// 根据评审意见生成后续提交的信息
+	fmt.Fprintln(stdout)
+	runCommit(cmdArgs{
+		lang:  lang,
+		notes: fmt.Sprintf("This commit addresses review feedback on pull request #%d (%s). Describe which of these requested changes it resolves:\n%s", number, pr.Title, summary),
+	})
+}
+
+// githubRepository 解析 GitHub 仓库的 owner 和 name，未通过 --repo 指定时从 origin 远程地址解析
+func githubRepository(repo string) (string, string, error) {
+	if repo == "" {
+		remote, err := gitOutput("remote", "get-url", "origin")
+		if err != nil {
+			return "", "", newError("pr.no_remote")
+		}
+		repo = strings.TrimSpace(remote)
+	} else {
+		repo = "/" + repo
+	}
+
+	match := githubRemotePattern.FindStringSubmatch(repo)
+	if match == nil {
+		return "", "", newError("pr.bad_repo", repo)
+	}
+	return match[1], match[2], nil
+}
+
+// githubToken 从环境变量或 gh 命令行工具获取 GitHub 令牌
+func githubToken() string {
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command("gh", "auth", "token")
+	cmd.Stdout = &output
+	if err := cmd.Run(); err == nil {
+		return strings.TrimSpace(output.String())
+	}
+	return ""
+}
+
+// githubGraphQLEndpoint GitHub GraphQL 接口地址，GitHub Enterprise 可通过 GITHUB_GRAPHQL_URL 指定
+func githubGraphQLEndpoint() string {
+	if endpoint := os.Getenv("GITHUB_GRAPHQL_URL"); endpoint != "" {
+		return endpoint
+	}
+	return defaultGitHubGraphQL
+}
+
+// fetchPullRequestReview 通过 GitHub GraphQL API 获取拉取请求的评审讨论
+func fetchPullRequestReview(owner, name string, number int) (pullRequestReview, error) {
+	token := githubToken()
+	if token == "" {
+		return pullRequestReview{}, newError("pr.no_token")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query": reviewThreadsQuery,
+		"variables": map[string]interface{}{
+			"owner":  owner,
+			"name":   name,
+			"number": number,
+		},
+	})
+	if err != nil {
+		return pullRequestReview{}, newError("api.marshal", err)
+	}
+
+	client, err := newHTTPClient(requestTimeout())
+	if err != nil {
+		return pullRequestReview{}, newError("api.client", err)
+	}
+
+	req, err := http.NewRequest("POST", githubGraphQLEndpoint(), bytes.NewReader(body))
+	if err != nil {
+		return pullRequestReview{}, newError("api.request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return pullRequestReview{}, newRetryableError("pr.request_failed", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return pullRequestReview{}, newRetryableError("api.read", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return pullRequestReview{}, statusError(resp.StatusCode, "pr.http_error", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		Data struct {
+			Repository struct {
+				PullRequest *pullRequestReview `json:"pullRequest"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return pullRequestReview{}, newError("api.unmarshal", err)
+	}
+	if len(result.Errors) > 0 {
+		return pullRequestReview{}, newError("pr.graphql_error", result.Errors[0].Message)
+	}
+	if result.Data.Repository.PullRequest == nil {
+		return pullRequestReview{}, newError("pr.not_found", number)
+	}
+
+	return *result.Data.Repository.PullRequest, nil
+}
+
+// outstandingThreads 返回未解决的评审讨论
+func outstandingThreads(pr pullRequestReview) []reviewThread {
+	var threads []reviewThread
+	for _, t := range pr.ReviewThreads.Nodes {
+		if !t.IsResolved && len(t.Comments.Nodes) > 0 {
+			threads = append(threads, t)
+		}
+	}
+	return threads
+}
+
+// changeRequests 返回要求修改的评审中非空的评审意见
+func changeRequests(pr pullRequestReview) []reviewComment {
+	var reviews []reviewComment
+	for _, r := range pr.Reviews.Nodes {
+		if strings.TrimSpace(r.Body) != "" {
+			reviews = append(reviews, r)
+		}
+	}
+	return reviews
+}
+
+// buildReviewSummaryPrompt 构建总结待处理评审意见的提示词
+func buildReviewSummaryPrompt(pr pullRequestReview, threads []reviewThread, lang string) string {
+	data := reviewPromptData{Title: pr.Title, Lang: lang}
+	for _, t := range threads {
+		location := t.Path
+		if t.Line > 0 {
+			location = fmt.Sprintf("%s:%d", t.Path, t.Line)
+		}
+		if t.IsOutdated {
+			location += " (outdated)"
+		}
+		thread := reviewPromptThread{Location: location}
+		for _, c := range t.Comments.Nodes {
+			thread.Comments = append(thread.Comments, newReviewPromptComment(c))
+		}
+		data.Threads = append(data.Threads, thread)
+	}
+	for _, r := range changeRequests(pr) {
+		data.Reviews = append(data.Reviews, newReviewPromptComment(r))
+	}
+	return renderTemplate("review_summary", data)
+}
+
+// newReviewPromptComment 转换为模板中使用的评审意见
+func newReviewPromptComment(c reviewComment) reviewPromptComment {
+	return reviewPromptComment{Author: c.Author.Login, Body: strings.TrimSpace(c.Body)}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30
+	defaultRetryDelay = 1
+
+	// profileEnv 未通过 --profile 指定时读取的环境变量
+	profileEnv = "AICOMMIT_PROFILE"
+)
+
+// Profile 服务商配置，覆盖顶层配置中的对应项
+//
+// 不同服务商需要不同的参数，例如本地的大模型需要 300 秒超时，OpenAI 只需 30 秒。
+type Profile struct {
+	OpenAIEndpoint string   `json:"openai_endpoint,omitempty"`
+	APIKey         string   `json:"api_key,omitempty"`
+	Model          string   `json:"model,omitempty"`
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+	Temperature    *float64 `json:"temperature,omitempty"`
+	TopP           *float64 `json:"top_p,omitempty"`
+	Timeout        int      `json:"timeout,omitempty"`
+	Retries        *int     `json:"retries,omitempty"`
+	RetryDelay     int      `json:"retry_delay,omitempty"`
+
+	OpenAIEndpoints  []string `json:"openai_endpoints,omitempty"`
+	EndpointStrategy string   `json:"endpoint_strategy,omitempty"`
+
+	ResponseContentPath string `json:"response_content_path,omitempty"`
+	PromptCache         string `json:"prompt_cache,omitempty"`
+	CommitAs            string `json:"commit_as,omitempty"`
+	AuthProvider        string `json:"auth_provider,omitempty"`
+
+	ProxyURL      string `json:"proxy_url,omitempty"`
+	ProxyUsername string `json:"proxy_username,omitempty"`
+	ProxyPassword string `json:"proxy_password,omitempty"`
+}
+
+// profileFlag 通过 --profile 指定的配置名
+var profileFlag string
+
+// selectedProfileName 返回本次使用的配置名：--profile 优先，其次是环境变量和配置文件中的 profile
+func selectedProfileName() string {
+	if profileFlag != "" {
+		return profileFlag
+	}
+	if name := os.Getenv(profileEnv); name != "" {
+		return name
+	}
+	return config.Profile
+}
+
+// selectProfile 查找本次使用的服务商配置，未选择时返回 nil
+func selectProfile() (*Profile, error) {
+	name := selectedProfileName()
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(config.Profiles))
+		for n := range config.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, newError("profile.unknown", name, strings.Join(names, ", "))
+	}
+	return &profile, nil
+}
+
+// applyProfile 用服务商配置覆盖顶层配置
+func applyProfile(p *Profile) {
+	// 服务商配置中的端点替换顶层配置中的所有端点
+	if p.OpenAIEndpoint != "" || len(p.OpenAIEndpoints) > 0 {
+		config.OpenAIEndpoint = p.OpenAIEndpoint
+		config.OpenAIEndpoints = p.OpenAIEndpoints
+	}
+	if p.EndpointStrategy != "" {
+		config.EndpointStrategy = p.EndpointStrategy
+	}
+	// 服务商配置中的密钥和网关登录二选一，替换顶层配置中的设置
+	if p.APIKey != "" {
+		config.APIKey = p.APIKey
+		config.AuthProvider = ""
+	}
+	if p.AuthProvider != "" {
+		config.AuthProvider = p.AuthProvider
+		config.APIKey = p.APIKey
+	}
+	if p.Model != "" {
+		config.Model = p.Model
+	}
+	if p.MaxTokens > 0 {
+		config.MaxTokens = p.MaxTokens
+	}
+	if p.Temperature != nil {
+		config.Temperature = *p.Temperature
+	}
+	if p.TopP != nil {
+		config.TopP = p.TopP
+	}
+	if p.Timeout > 0 {
+		config.Timeout = p.Timeout
+	}
+	// retries 可以为 0，例如用于快速生成、从不重试的配置
+	if p.Retries != nil {
+		config.Retries = *p.Retries
+	}
+	if p.RetryDelay > 0 {
+		config.RetryDelay = p.RetryDelay
+	}
+	if p.ResponseContentPath != "" {
+		config.ResponseContentPath = p.ResponseContentPath
+	}
+	if p.PromptCache != "" {
+		config.PromptCache = p.PromptCache
+	}
+	if p.CommitAs != "" {
+		config.CommitAs = p.CommitAs
+	}
+	// 代理的认证信息属于服务商配置中的代理，不与顶层配置中的混用
+	if p.ProxyURL != "" {
+		config.ProxyURL = p.ProxyURL
+		config.ProxyUsername = p.ProxyUsername
+		config.ProxyPassword = p.ProxyPassword
+	}
+}
+
+// requestTimeout 单次 API 请求的超时时间
+func requestTimeout() time.Duration {
+	if config.Timeout > 0 {
+		return time.Duration(config.Timeout) * time.Second
+	}
+	return defaultTimeout * time.Second
+}
+
+// retryDelay 第 attempt 次重试前的等待时间，按指数退避
+func retryDelay(attempt int) time.Duration {
+	delay := defaultRetryDelay
+	if config.RetryDelay > 0 {
+		delay = config.RetryDelay
+	}
+	return time.Duration(delay) * time.Second << attempt
+}
+
+// isRetryableStatus 判断 HTTP 状态码是否值得重试
+func isRetryableStatus(status int) bool {
+	return status == 429 || status >= 500
+}
@@ -0,0 +1,72 @@
+package main
+
+const (
+	promptCacheOff     = "off"
+	promptCachePrefix  = "prefix"
+	promptCacheControl = "cache_control"
+)
+
+// chatPrompt 发送给模型的提示词
+//
+// prefix 是各次提交之间不变的说明和风格指南，开启 prompt_cache 时作为 system 消息放在最前面，
+// 服务商可以缓存这段前缀；未开启时 prefix 为空，全部内容在 text 中。
+type chatPrompt struct {
+	prefix string
+	text   string
+}
+
+// contentPart 分段的消息内容，用于附加 cache_control
+type contentPart struct {
+	Type         string        `json:"type"`
+	Text         string        `json:"text"`
+	CacheControl *cacheControl `json:"cache_control,omitempty"`
+}
+
+type cacheControl struct {
+	Type string `json:"type"`
+}
+
+// promptCacheMode 返回生效的提示词缓存方式
+func promptCacheMode() string {
+	if config.PromptCache == "" {
+		return promptCacheOff
+	}
+	return config.PromptCache
+}
+
+// chatMessages 构建请求中的消息列表
+//
+// prefix 模式下依靠 OpenAI、DeepSeek 等服务的自动前缀缓存，只需保证前缀每次相同；
+// cache_control 模式下额外为 system 消息加上 Anthropic 格式的缓存标记，OpenRouter 等网关也会转发该标记。
+func chatMessages(prompt chatPrompt) []message {
+	if prompt.prefix == "" {
+		return []message{{Role: "user", Content: prompt.text}}
+	}
+
+	var system interface{} = prompt.prefix
+	if promptCacheMode() == promptCacheControl {
+		system = []contentPart{{Type: "text", Text: prompt.prefix, CacheControl: &cacheControl{Type: "ephemeral"}}}
+	}
+	return []message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: prompt.text},
+	}
+}
+
+// buildCachedPrompt 构建便于缓存的提示词，不变的说明、gitmoji 规则和风格指南放在前缀中
+func buildCachedPrompt(ctx promptContext) chatPrompt {
+	data := newPromptData(ctx)
+	return chatPrompt{
+		prefix: renderTemplate("commit_prefix", data),
+		text:   renderTemplate("commit_cached", data),
+	}
+}
+
+// validatePromptCache 校验 prompt_cache，用户配置和服务商配置共用
+func validatePromptCache(key, value string, lines map[string]int) []configIssue {
+	switch value {
+	case "", promptCacheOff, promptCachePrefix, promptCacheControl:
+		return nil
+	}
+	return []configIssue{{line: lines[key], key: key, message: tr("schema.invalid_choice", promptCacheOff+", "+promptCachePrefix+", "+promptCacheControl)}}
+}
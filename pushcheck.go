@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// zeroSHA pre-push 钩子中表示不存在的提交
+	zeroSHA = "0000000000000000000000000000000000000000"
+
+	// maxPushCheckCommits 一次推送中最多列出和摘要的提交数
+	maxPushCheckCommits = 200
+
+	pushHookName   = "pre-push"
+	pushHookMarker = "# installed by aicommit push-check --install"
+)
+
+// defaultProtectedBranches 未配置 protected_branches 时受保护的分支
+var defaultProtectedBranches = []string{"main", "master"}
+
+// pushUpdate pre-push 钩子从标准输入读到的一行，即将更新的一个远程引用
+type pushUpdate struct {
+	localRef  string
+	localSHA  string
+	remoteRef string
+	remoteSHA string
+}
+
+func runPushCheck(args []string) {
+	install := false
+	yes := false
+	var positional []string
+
+	for _, arg := range args {
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if arg == "--install" {
+			install = true
+		} else if arg == "--yes" || arg == "-y" {
+			yes = true
+		} else if strings.HasPrefix(arg, "-") || len(positional) == 2 {
+			failUsage("arg.unknown", arg)
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	ensureGitRepository()
+
+	if install {
+		installPushHook()
+		return
+	}
+
+	remote := "origin"
+	if len(positional) > 0 {
+		remote = positional[0]
+	}
+
+	if err := loadRepoConfig(); err != nil {
+		fail("err.load_repo_config", err)
+	}
+	// 没有可用的配置或密钥时仍列出提交并要求确认，只是不生成摘要
+	configErr := loadPushCheckConfig()
+	if len(repoConfig.ProtectedBranches) > 0 {
+		config.ProtectedBranches = repoConfig.ProtectedBranches
+	}
+
+	var updates []pushUpdate
+	if isTerminal(os.Stdin) {
+		// 手动运行时检查当前分支推送到同名远程分支的内容
+		updates = currentBranchUpdate(remote)
+	} else {
+		updates = readPushUpdates()
+	}
+
+	checked := false
+	for _, u := range updates {
+		branch, ok := strings.CutPrefix(u.remoteRef, "refs/heads/")
+		if !ok || !isProtectedBranch(branch) {
+			continue
+		}
+		checked = true
+
+		if u.localSHA == zeroSHA {
+			fmt.Fprintln(stdout, tr("pushcheck.delete", remote, branch))
+		} else {
+			summarizePush(remote, branch, u, configErr)
+		}
+		if !yes {
+			confirmPush(branch)
+		}
+	}
+
+	if !checked && isTerminal(os.Stdin) {
+		fmt.Fprintln(stdout, tr("pushcheck.not_protected", remote, strings.Join(protectedBranches(), ", ")))
+	}
+}
+
+// loadPushCheckConfig 读取配置，与 loadConfig 不同，缺少配置文件或密钥时返回错误而不退出
+func loadPushCheckConfig() error {
+	readOptionalConfig()
+	if config.APIKey == "" && config.AuthProvider != "" {
+		token, err := accessToken(config.AuthProvider)
+		if err != nil {
+			return err
+		}
+		config.APIKey = token
+	}
+	if config.APIKey == "" {
+		return newError("pushcheck.no_api_key")
+	}
+	return nil
+}
+
+// protectedBranches 返回受保护分支的模式
+func protectedBranches() []string {
+	if len(config.ProtectedBranches) > 0 {
+		return config.ProtectedBranches
+	}
+	return defaultProtectedBranches
+}
+
+// isProtectedBranch 判断分支是否匹配受保护分支的模式，模式支持 release/* 等通配符
+func isProtectedBranch(branch string) bool {
+	for _, pattern := range protectedBranches() {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// readPushUpdates 读取 pre-push 钩子标准输入中的引用，每行为 "<本地引用> <本地提交> <远程引用> <远程提交>"
+func readPushUpdates() []pushUpdate {
+	var updates []pushUpdate
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		updates = append(updates, pushUpdate{localRef: fields[0], localSHA: fields[1], remoteRef: fields[2], remoteSHA: fields[3]})
+	}
+	return updates
+}
+
+// currentBranchUpdate 构造把当前分支推送到远程同名分支时的引用
+func currentBranchUpdate(remote string) []pushUpdate {
+	branch := currentBranch()
+	if branch == "" {
+		return nil
+	}
+	local, err := gitOutput("rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		return nil
+	}
+	remoteSHA := zeroSHA
+	if output, err := gitOutput("rev-parse", "--verify", "-q", "refs/remotes/"+remote+"/"+branch); err == nil {
+		remoteSHA = strings.TrimSpace(output)
+	}
+	return []pushUpdate{{
+		localRef:  "refs/heads/" + branch,
+		localSHA:  strings.TrimSpace(local),
+		remoteRef: "refs/heads/" + branch,
+		remoteSHA: remoteSHA,
+	}}
+}
+
+// pushLogArgs 返回列出即将推送的提交的 git log 参数
+//
+// 远程分支还不存在时列出远程仓库中还没有的提交。
+func pushLogArgs(remote string, u pushUpdate) []string {
+	args := []string{"log", "-p", "--no-merges", "--no-color", "-n", fmt.Sprint(maxPushCheckCommits)}
+	if u.remoteSHA == zeroSHA {
+		return append(args, u.localSHA, "--not", "--remotes="+remote)
+	}
+	return append(args, u.remoteSHA+".."+u.localSHA)
+}
+
+// summarizePush 列出即将推送到受保护分支的提交、标出可疑的提交并由模型总结
+func summarizePush(remote, branch string, u pushUpdate, configErr error) {
+	commits := getDigestCommits(pushLogArgs(remote, u))
+	if len(commits) == 0 {
+		fmt.Fprintln(stdout, tr("pushcheck.no_commits", remote, branch))
+		return
+	}
+
+	fmt.Fprintln(stdout, tr("pushcheck.header", len(commits), remote, branch))
+	for _, c := range commits {
+		fmt.Fprintf(stdout, "  %s %s (%s)\n", c.hash, c.subject, c.author)
+	}
+
+	// 沿用 lint 的规则标出 WIP、fixup! 等不应推送的提交
+	var flagged []string
+	for _, c := range commits {
+		for _, issue := range lintMessage(c.subject, LintConfig{}) {
+			if issue.rule == "fixup" || issue.rule == "subject-vague" {
+				flagged = append(flagged, fmt.Sprintf("  %s %s: %s", c.hash, c.subject, issue.message))
+				break
+			}
+		}
+	}
+	if len(flagged) > 0 {
+		fmt.Fprintln(stdout)
+		fmt.Fprintln(stdout, tr("pushcheck.flagged"))
+		fmt.Fprintln(stdout, strings.Join(flagged, "\n"))
+	}
+
+	if configErr != nil {
+		fmt.Fprintln(stderr, tr("pushcheck.no_summary", configErr))
+		return
+	}
+
+	fmt.Fprintln(stderr, tr("pushcheck.summarizing"))
+	summary, err := pushSummary(branch, commits)
+	if err != nil {
+		fmt.Fprintln(stderr, tr("pushcheck.no_summary", err))
+		return
+	}
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, summary)
+}
+
+// pushSummary 请模型总结即将推送的提交，补丁过大时先分段摘要
+func pushSummary(branch string, commits []digestCommit) (string, error) {
+	changes := joinDigestPatches(commits)
+	if len(changes) > maxDiffChars() {
+		files := make([]fileDiff, len(commits))
+		for i, c := range commits {
+			files[i] = fileDiff{path: c.hash, text: c.patch}
+		}
+		summary, err := summarizeCommits(files, buildDigestChunkPrompt, nil)
+		if err != nil {
+			return "", err
+		}
+		changes = summary
+	}
+	return requestCompletion(buildPushPrompt(branch, commits, changes))
+}
+
+// buildPushPrompt 构建总结推送内容的提示词
+func buildPushPrompt(branch string, commits []digestCommit, changes string) string {
+	return renderTemplate("push_check", pushPromptData{
+		Branch:  branch,
+		Commits: templateCommits(commits),
+		Changes: changes,
+		Lang:    config.DefaultLang,
+	})
+}
+
+// confirmPush 在终端中确认推送，取消或无法确认时以非零状态退出，阻止 git push
+//
+// 钩子的标准输入是 git 传入的引用列表，因此从控制终端读取回答。
+func confirmPush(branch string) {
+	input, err := openTerminalInput()
+	if err != nil {
+		fail("pushcheck.no_terminal", branch)
+	}
+	defer input.Close()
+
+	fmt.Fprintln(stdout)
+	fmt.Fprint(stdout, tr("pushcheck.confirm", branch))
+	answer, _ := bufio.NewReader(input).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return
+	}
+	fail("pushcheck.aborted")
+}
+
+// installPushHook 安装调用 aicommit push-check 的 pre-push 钩子，不覆盖其他工具的钩子
+func installPushHook() {
+	hooksDir, err := gitPath("hooks")
+	if err != nil {
+		fail("err.git", err)
+	}
+	hookPath := filepath.Join(hooksDir, pushHookName)
+	if content, err := os.ReadFile(hookPath); err == nil && !strings.Contains(string(content), pushHookMarker) {
+		fail("pushcheck.hook_exists", hookPath)
+	}
+
+	executable := "aicommit"
+	if path, err := os.Executable(); err == nil {
+		executable = filepath.ToSlash(path)
+	}
+	script := fmt.Sprintf("#!/bin/sh\n%s\nexec %q push-check \"$@\"\n", pushHookMarker, executable)
+
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		fail("pushcheck.install_failed", err)
+	}
+	if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		fail("pushcheck.install_failed", err)
+	}
+	fmt.Fprintln(stdout, tr("pushcheck.installed", hookPath))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	refineCritiqueMarker = "CRITIQUE:"
+	refineMessageMarker  = "MESSAGE:"
+)
+
+// buildRefinePrompt 构建自我审查的提示词：对照差异检查草稿是否遗漏或描述错误，并给出修改后的提交信息
+//
+// 模板需要让模型按 CRITIQUE:/MESSAGE: 格式回答，parseRefineResponse 依赖这两个标记。
+func buildRefinePrompt(ctx promptContext, draft string) string {
+	data := newPromptData(ctx)
+	data.Draft = draft
+	return renderTemplate("refine", data)
+}
+
+// parseRefineResponse 从审查结果中取出问题列表和修改后的提交信息，格式不符时返回空信息
+func parseRefineResponse(content string) (string, string) {
+	critique, message, found := strings.Cut(content, refineMessageMarker)
+	if !found {
+		return "", ""
+	}
+	critique = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(critique), refineCritiqueMarker))
+
+	message = strings.TrimSpace(message)
+	message = strings.TrimPrefix(message, `"`)
+	message = strings.TrimSuffix(message, `"`)
+	return critique, strings.TrimSpace(message)
+}
+
+// refineMessage 按 refine_passes 进行自我审查，每轮多调用一次模型
+//
+// 审查失败或超过截止时间时保留上一版提交信息；某一轮没有修改时提前结束。
+func refineMessage(ctx promptContext, draft string) string {
+	message := draft
+	for pass := 1; pass <= config.RefinePasses; pass++ {
+		fmt.Fprintln(stdout, tr("refine.pass", pass, config.RefinePasses))
+
+		content, err := requestCompletion(buildRefinePrompt(ctx, message))
+		if errors.Is(err, errDeadlineExceeded) {
+			break
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, tr("refine.failed", err))
+			break
+		}
+
+		critique, revised := parseRefineResponse(content)
+		if revised == "" {
+			fmt.Fprintln(stderr, tr("refine.unparsable"))
+			break
+		}
+		if critique != "" && !strings.EqualFold(strings.Trim(critique, "- "), "none") {
+			fmt.Fprintln(stdout, critique)
+		}
+		if revised == message {
+			break
+		}
+		message = revised
+	}
+	return message
+}
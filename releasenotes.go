@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	audienceUsers      = "users"
+	audienceDevelopers = "developers"
+	formatMarkdown     = "markdown"
+	formatText         = "text"
+)
+
+// releaseCommit 发布范围内的提交
+type releaseCommit struct {
+	hash    string
+	subject string
+	body    string
+}
+
+func runReleaseNotes(args []string) {
+	from, to := "", "HEAD"
+	audience := audienceUsers
+	format := formatMarkdown
+	lang := ""
+	resume := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--help" || arg == "-h" {
+			printHelp()
+			os.Exit(0)
+		} else if arg == "--resume" {
+			resume = true
+		} else if value, ok := flagValue(args, &i, "--from"); ok {
+			from = value
+		} else if value, ok := flagValue(args, &i, "--to"); ok {
+			to = value
+		} else if value, ok := flagValue(args, &i, "--audience"); ok {
+			audience = value
+		} else if value, ok := flagValue(args, &i, "--format"); ok {
+			format = value
+		} else if value, ok := flagValue(args, &i, "--lang"); ok {
+			lang = value
+		} else {
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	if audience != audienceUsers && audience != audienceDevelopers {
+		fail("arg.invalid_choice", "--audience", audience, audienceUsers+"|"+audienceDevelopers)
+	}
+	if format != formatMarkdown && format != formatText {
+		fail("arg.invalid_choice", "--format", format, formatMarkdown+"|"+formatText)
+	}
+
+	ensureGitRepository()
+
+	if err := loadConfig(); err != nil {
+		fail("err.load_config", err)
+	}
+	if lang == "" {
+		lang = config.DefaultLang
+	}
+
+	// 未指定起点时使用终点之前最近的标签
+	if from == "" {
+		tag, err := gitOutput("describe", "--tags", "--abbrev=0", to+"^")
+		if err != nil {
+			fail("release.no_tag")
+		}
+		from = strings.TrimSpace(tag)
+	}
+
+	commits := getReleaseCommits(from, to)
+	if len(commits) == 0 {
+		fmt.Fprintln(stdout, tr("release.no_commits", from, to))
+		os.Exit(0)
+	}
+
+	fmt.Fprintln(stderr, tr("release.generating", len(commits), from, to))
+
+	// 范围很大时沿用分段摘要，先把每段提交整理为变更条目
+	entries := joinReleaseEntries(commits)
+	var queue *jobQueue
+	if len(entries) > maxDiffChars() {
+		files := make([]fileDiff, len(commits))
+		for i, c := range commits {
+			files[i] = fileDiff{path: c.hash, text: releaseEntry(c)}
+		}
+		queue = openJobQueue("release-notes", resume)
+		summary, err := summarizeCommits(files, buildReleaseChunkPrompt, queue)
+		if err != nil {
+			failWith(err)
+		}
+		entries = summary
+	}
+
+	notes, err := requestCompletion(buildReleaseNotesPrompt(entries, from, to, audience, format, lang))
+	if err != nil {
+		failWith(err)
+	}
+	if notes == "" {
+		fail("release.unable")
+	}
+	queue.finish()
+
+	fmt.Fprintln(stdout, notes)
+}
+
+// flagValue 解析 `--name=value` 或 `--name value` 形式的参数
+func flagValue(args []string, i *int, name string) (string, bool) {
+	arg := args[*i]
+	if strings.HasPrefix(arg, name+"=") {
+		return strings.TrimPrefix(arg, name+"="), true
+	}
+	if arg == name {
+		if *i+1 >= len(args) {
+			fail("arg.missing_value", name)
+		}
+		*i++
+		return args[*i], true
+	}
+	return "", false
+}
+
+// getReleaseCommits 获取范围内的非合并提交
+func getReleaseCommits(from, to string) []releaseCommit {
+	output := runGitCommand("log", "--no-merges", "--format=%h%x00%s%x00%b%x1e", from+".."+to)
+
+	var commits []releaseCommit
+	for _, record := range strings.Split(output, "\x1e") {
+		parts := strings.SplitN(strings.TrimSpace(record), "\x00", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		commits = append(commits, releaseCommit{
+			hash:    parts[0],
+			subject: parts[1],
+			body:    strings.TrimSpace(parts[2]),
+		})
+	}
+
+	return commits
+}
+
+// releaseEntry 返回提示词中一个提交的条目，正文缩进放在标题下
+func releaseEntry(c releaseCommit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "- %s %s\n", c.hash, c.subject)
+	if c.body != "" {
+		for _, line := range strings.Split(c.body, "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// joinReleaseEntries 连接所有提交的条目
+func joinReleaseEntries(commits []releaseCommit) string {
+	var b strings.Builder
+	for _, c := range commits {
+		b.WriteString(releaseEntry(c))
+	}
+	return b.String()
+}
+
+// buildReleaseChunkPrompt 构建一段提交的摘要提示词
+func buildReleaseChunkPrompt(chunk diffChunk) string {
+	return renderTemplate("release_chunk", promptData{Diff: chunk.text})
+}
+
+// buildReleaseNotesPrompt 构建生成发布说明的提示词，entries 为提交列表或分段摘要
+func buildReleaseNotesPrompt(entries, from, to, audience, format, lang string) string {
+	return renderTemplate("release_notes", releaseNotesPromptData{
+		Entries:  entries,
+		From:     from,
+		To:       to,
+		Audience: audience,
+		Format:   format,
+		Lang:     lang,
+	})
+}
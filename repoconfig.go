@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+const (
+	repoConfigFileName = ".aicommit.json"
+
+	// repoStateDirName .git 中保存本地状态的目录，不随仓库共享
+	repoStateDirName = "aicommit"
+)
+
+// RepoConfig 仓库级配置结构体，保存在仓库根目录，可随仓库共享
+//
+// 仓库级配置只包含与提交风格相关的设置，端点、密钥等敏感配置
+// 只能在用户配置文件中设置，避免克隆的仓库篡改请求目标。
+type RepoConfig struct {
+	StyleGuide  string            `json:"style_guide,omitempty"`
+	ScopeSource string            `json:"scope_source,omitempty"`
+	ScopeMap    map[string]string `json:"scope_map,omitempty"`
+	OwnerScopes map[string]string `json:"owner_scopes,omitempty"`
+
+	IncludeTestPlan bool   `json:"include_test_plan,omitempty"`
+	DiffTable       bool   `json:"diff_table,omitempty"`
+	OriginReference string `json:"origin_reference,omitempty"`
+
+	Gitmoji          bool              `json:"gitmoji,omitempty"`
+	EmojiMap         map[string]string `json:"emoji_map,omitempty"`
+	DisallowedEmojis []string          `json:"disallowed_emojis,omitempty"`
+
+	SubjectCase string `json:"subject_case,omitempty"`
+	Tense       string `json:"tense,omitempty"`
+
+	WarnLargeCommitFiles *int `json:"warn_large_commit_files,omitempty"`
+
+	Trailers map[string]string `json:"trailers,omitempty"`
+
+	SensitiveFiles    []string `json:"sensitive_files,omitempty"`
+	ProtectedBranches []string `json:"protected_branches,omitempty"`
+
+	Lint *LintConfig `json:"lint,omitempty"`
+}
+
+var repoConfig RepoConfig
+
+// getRepoConfigFilePath 获取仓库级配置文件路径
+func getRepoConfigFilePath() (string, error) {
+	root, err := currentVCS.root()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(root, repoConfigFileName), nil
+}
+
+// repoStatePath 返回 .git/aicommit/ 中状态文件的路径并创建目录，工作树中使用主仓库的目录
+func repoStatePath(name string) (string, error) {
+	meta, err := currentVCS.metaDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(meta, repoStateDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// loadRepoConfig 加载仓库级配置，文件不存在时忽略
+func loadRepoConfig() error {
+	configPath, err := getRepoConfigFilePath()
+	if err != nil {
+		// 不在仓库中时没有仓库级配置
+		return nil
+	}
+
+	jsonData, err := readConfigFile(configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	parsed, err := checkRepoConfigFile(configPath, jsonData)
+	if err != nil {
+		return err
+	}
+	repoConfig = parsed
+	return nil
+}
+
+// saveRepoConfig 保存仓库级配置
+func saveRepoConfig() (string, error) {
+	configPath, err := getRepoConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+
+	jsonData, err := json.MarshalIndent(repoConfig, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(configPath, append(jsonData, '\n'), 0644); err != nil {
+		return "", err
+	}
+
+	return configPath, nil
+}
+
+// applyRepoConfig 用仓库级配置覆盖用户配置中的对应项
+func applyRepoConfig() {
+	if repoConfig.ScopeSource != "" {
+		config.ScopeSource = repoConfig.ScopeSource
+	}
+	if len(repoConfig.ScopeMap) > 0 {
+		config.ScopeMap = repoConfig.ScopeMap
+	}
+	if len(repoConfig.OwnerScopes) > 0 {
+		config.OwnerScopes = repoConfig.OwnerScopes
+	}
+	if repoConfig.IncludeTestPlan {
+		config.IncludeTestPlan = true
+	}
+	if repoConfig.DiffTable {
+		config.DiffTable = true
+	}
+	if repoConfig.OriginReference != "" {
+		config.OriginReference = repoConfig.OriginReference
+	}
+	if repoConfig.Gitmoji {
+		config.Gitmoji = true
+	}
+	if len(repoConfig.EmojiMap) > 0 {
+		config.EmojiMap = repoConfig.EmojiMap
+	}
+	if len(repoConfig.DisallowedEmojis) > 0 {
+		config.DisallowedEmojis = repoConfig.DisallowedEmojis
+	}
+	if repoConfig.SubjectCase != "" {
+		config.SubjectCase = repoConfig.SubjectCase
+	}
+	if repoConfig.Tense != "" {
+		config.Tense = repoConfig.Tense
+	}
+	if repoConfig.WarnLargeCommitFiles != nil {
+		config.WarnLargeCommitFiles = *repoConfig.WarnLargeCommitFiles
+	}
+	if len(repoConfig.Trailers) > 0 {
+		config.Trailers = repoConfig.Trailers
+	}
+}
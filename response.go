@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultContentPaths 未配置 response_content_path 时依次尝试的内容位置
+//
+// 除标准的 OpenAI 格式外，兼容把结果包在 data 中的网关、旧版 completions 接口和 Ollama 原生接口。
+var defaultContentPaths = []string{
+	"choices[0].message.content",
+	"data.choices[0].message.content",
+	"choices[0].text",
+	"data.choices[0].text",
+	"message.content",
+	"response",
+	"output_text",
+}
+
+// defaultDeltaPaths 流式响应中每个分块的内容位置
+var defaultDeltaPaths = []string{
+	"choices[0].delta.content",
+	"data.choices[0].delta.content",
+	"message.content",
+	"response",
+}
+
+// defaultErrorPaths 错误信息的位置，error 既可能是对象也可能是字符串
+var defaultErrorPaths = []string{"error.message", "error", "data.error.message", "errors[0].message"}
+
+// httpErrorPaths HTTP 状态码表示失败时额外尝试的错误信息位置
+var httpErrorPaths = []string{"detail", "message", "msg"}
+
+// usagePaths 令牌用量的位置
+var usagePaths = []string{"usage", "data.usage"}
+
+// pathStep 路径中的一步：对象的键或数组的下标
+type pathStep struct {
+	key   string
+	index int
+}
+
+// parseResponsePath 解析 $.data.choices[0].message.content 形式的路径，开头的 $ 可以省略
+func parseResponsePath(path string) ([]pathStep, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil, newError("response.empty_path")
+	}
+
+	var steps []pathStep
+	for _, segment := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(segment, "[")
+		if key == "" && rest == "" {
+			return nil, newError("response.bad_path", path)
+		}
+		if key != "" {
+			steps = append(steps, pathStep{key: key})
+		}
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, newError("response.bad_path", path)
+			}
+			index, err := strconv.Atoi(rest[:end])
+			if err != nil || index < 0 {
+				return nil, newError("response.bad_path", path)
+			}
+			steps = append(steps, pathStep{index: index})
+			rest = strings.TrimPrefix(rest[end+1:], "[")
+		}
+	}
+	return steps, nil
+}
+
+// lookupPath 按路径从解析后的 JSON 中取值，路径不存在时返回 false
+func lookupPath(value interface{}, path string) (interface{}, bool) {
+	steps, err := parseResponsePath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, step := range steps {
+		if step.key != "" {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if value, ok = object[step.key]; !ok {
+				return nil, false
+			}
+			continue
+		}
+		array, ok := value.([]interface{})
+		if !ok || step.index >= len(array) {
+			return nil, false
+		}
+		value = array[step.index]
+	}
+	return value, value != nil
+}
+
+// lookupString 依次尝试各个路径，返回第一个字符串值
+func lookupString(value interface{}, paths []string) (string, bool) {
+	for _, path := range paths {
+		if s, ok := lookupPath(value, path); ok {
+			if text, ok := s.(string); ok {
+				return text, true
+			}
+		}
+	}
+	return "", false
+}
+
+// responseErrorMessage 从响应中取出错误信息，没有错误时返回空
+func responseErrorMessage(value interface{}, status int) string {
+	paths := defaultErrorPaths
+	if status >= 400 {
+		paths = append(append([]string{}, defaultErrorPaths...), httpErrorPaths...)
+	}
+	if message, ok := lookupString(value, paths); ok && message != "" {
+		return message
+	}
+
+	// error 字段是没有 message 的对象时原样输出
+	if object, ok := lookupPath(value, "error"); ok {
+		if data, err := json.Marshal(object); err == nil {
+			return string(data)
+		}
+	}
+	return ""
+}
+
+// responseUsage 从响应中取出令牌用量
+func responseUsage(value interface{}) usage {
+	var result usage
+	for _, path := range usagePaths {
+		if raw, ok := lookupPath(value, path); ok {
+			if data, err := json.Marshal(raw); err == nil && json.Unmarshal(data, &result) == nil {
+				break
+			}
+		}
+	}
+	return result
+}
+
+// responseContentPaths 返回本次使用的内容位置
+func responseContentPaths() []string {
+	if config.ResponseContentPath != "" {
+		return []string{config.ResponseContentPath}
+	}
+	return defaultContentPaths
+}
+
+// parseChatResponse 解析非流式响应
+//
+// 配置了 response_content_path 时只从该位置取内容，找不到时报错，便于排查配置问题。
+func parseChatResponse(body []byte, status int) (completion, error) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		if status >= 400 {
+			return completion{}, statusError(status, "api.http_error", status, strings.TrimSpace(string(body)))
+		}
+		return completion{}, newError("api.unmarshal", err)
+	}
+
+	if message := responseErrorMessage(value, status); message != "" {
+		return completion{}, statusError(status, "api.error", message)
+	}
+	if status >= 400 {
+		return completion{}, statusError(status, "api.http_error", status, strings.TrimSpace(string(body)))
+	}
+
+	result := completion{usage: responseUsage(value)}
+	content, ok := lookupString(value, responseContentPaths())
+	if !ok && config.ResponseContentPath != "" {
+		return completion{}, newError("response.path_not_found", config.ResponseContentPath, truncateBody(body))
+	}
+	result.content = strings.TrimSpace(normalizeNewlines(content))
+
+	return result, nil
+}
+
+// parseChatChunk 解析流式响应中的一个分块
+func parseChatChunk(data string) (string, *usage, error) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return "", nil, newError("api.unmarshal", err)
+	}
+	if message := responseErrorMessage(value, 0); message != "" {
+		return "", nil, newError("api.error", message)
+	}
+
+	var chunkUsage *usage
+	if u := responseUsage(value); u != (usage{}) {
+		chunkUsage = &u
+	}
+	content, _ := lookupString(value, defaultDeltaPaths)
+	return content, chunkUsage, nil
+}
+
+// truncateBody 截断错误信息中的响应内容
+func truncateBody(body []byte) string {
+	const limit = 300
+	text := strings.TrimSpace(string(body))
+	if len(text) > limit {
+		return fmt.Sprintf("%s... (%d bytes)", text[:runeBoundary(text, limit)], len(body))
+	}
+	return text
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseResponsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []pathStep
+		wantErr bool
+	}{
+		{"$.choices[0].message.content", []pathStep{{key: "choices"}, {index: 0}, {key: "message"}, {key: "content"}}, false},
+		{"choices[0].message.content", []pathStep{{key: "choices"}, {index: 0}, {key: "message"}, {key: "content"}}, false},
+		{" $.data.text ", []pathStep{{key: "data"}, {key: "text"}}, false},
+		{"$[1]", []pathStep{{index: 1}}, false},
+		{"output[0][2].text", []pathStep{{key: "output"}, {index: 0}, {index: 2}, {key: "text"}}, false},
+		{"result", []pathStep{{key: "result"}}, false},
+		{"", nil, true},
+		{"$", nil, true},
+		{"a..b", nil, true},
+		{"a[0", nil, true},
+		{"a[x]", nil, true},
+		{"a[-1]", nil, true},
+		{"a[]", nil, true},
+		{"a[0]b", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseResponsePath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseResponsePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseResponsePath(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	var body interface{}
+	if err := json.Unmarshal([]byte(`{"data":{"choices":[{"text":"feat: a"},{"text":null}]},"n":1}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"$.data.choices[0].text", "feat: a", true},
+		{"data.choices[1].text", nil, false},
+		{"data.choices[2].text", nil, false},
+		{"data.missing", nil, false},
+		{"n.text", nil, false},
+		{"data[0]", nil, false},
+		{"n", float64(1), true},
+		{"a[", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := lookupPath(body, tt.path)
+		if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lookupPath(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	restructureRemoval = "removal"
+	restructureMove    = "move"
+
+	// removalRatio 删除文件的行数至少占所有改动行数的比例时，视为以删除为主
+	removalRatio = 0.8
+)
+
+// fileRename 重命名或移动的文件
+type fileRename struct {
+	from string
+	to   string
+}
+
+// restructureInfo 以删除文件或移动文件为主的改动，kind 为空表示不是这类改动
+type restructureInfo struct {
+	kind    string
+	deleted []string
+	renamed []fileRename
+}
+
+// restructureVerbs 描述删除和移动意图的词，用于校验生成的标题
+var restructureVerbs = map[string][]string{
+	restructureRemoval: {"remov", "delet", "drop", "deprecat", "clean", "prune", "strip", "retire", "删除", "移除", "去掉", "去除", "清理", "废弃", "下线"},
+	restructureMove:    {"mov", "renam", "relocat", "restructur", "reorganiz", "split", "extract", "merg", "flatten", "移动", "移到", "重命名", "改名", "迁移", "重构", "调整", "拆分", "合并", "整理"},
+}
+
+// analyzeRestructure 判断改动是否以删除文件或移动目录为主
+//
+// 删除文件的差异由大量 "-" 行组成，模型容易逐行复述被删掉的内容；重命名较多时也容易写成
+// 逐个文件的修改列表。删除文件的行数占绝大部分，或至少一半的文件是重命名时，改为提示模型描述意图。
+func analyzeRestructure(files []fileDiff) restructureInfo {
+	var info restructureInfo
+	total, removed := 0, 0
+	for _, f := range files {
+		_, hunks := splitHunks(f.text)
+		added, deleted := diffStat(hunks)
+		total += added + deleted
+
+		if isDeletedFile(f) {
+			info.deleted = append(info.deleted, f.path)
+			removed += deleted
+		} else if from := renamedFrom(f); from != f.path {
+			info.renamed = append(info.renamed, fileRename{from: from, to: f.path})
+		}
+	}
+
+	switch {
+	case len(info.deleted) > 0 && float64(removed) >= removalRatio*float64(total):
+		info.kind = restructureRemoval
+	case len(info.renamed) > 0 && len(info.renamed)*2 >= len(files):
+		info.kind = restructureMove
+	}
+	return info
+}
+
+// isDeletedFile 判断文件差异是否为删除整个文件
+func isDeletedFile(f fileDiff) bool {
+	return strings.Contains(f.text, "\ndeleted file mode ")
+}
+
+// collapseDeletedFile 将删除文件的内容替换为统计信息，只保留头部
+func collapseDeletedFile(f fileDiff) string {
+	header, hunks := splitHunks(f.text)
+	_, deleted := diffStat(hunks)
+	return fmt.Sprintf("%s\n%s | file deleted, %d lines removed (content omitted)", header, f.path, deleted)
+}
+
+// restructureHint 提示模型描述删除或重组的意图，而不是列举被删除的行
+func restructureHint(info restructureInfo) string {
+	switch info.kind {
+	case restructureRemoval:
+		return fmt.Sprintf("This change mainly deletes files: %s. Their content was omitted. Describe why they are removed (for example \"Remove deprecated X module\") instead of listing what the deleted code did line by line.", strings.Join(info.deleted, ", "))
+	case restructureMove:
+		moves := make([]string, len(info.renamed))
+		for i, r := range info.renamed {
+			moves[i] = r.from + " -> " + r.to
+		}
+		return fmt.Sprintf("This change mainly moves or renames files: %s. Describe the restructuring as a whole (for example \"Move X into Y\") instead of listing each file.", strings.Join(moves, ", "))
+	}
+	return ""
+}
+
+// describesRestructure 判断标题是否描述了删除或移动的意图
+//
+// 只能识别英文和中文的用词，其他语言的信息不做校验。
+func describesRestructure(message string, info restructureInfo, lang string) bool {
+	lang = strings.ToLower(lang)
+	if info.kind == "" || !(strings.HasPrefix(lang, "en") || strings.HasPrefix(lang, "zh") || strings.Contains(lang, "chinese") || strings.Contains(lang, "中文")) {
+		return true
+	}
+
+	subject := strings.ToLower(messageSubject(message))
+	for _, verb := range restructureVerbs[info.kind] {
+		if strings.Contains(subject, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// restructureMessage 生成的标题没有描述意图时，根据删除或移动的文件在本地生成标题
+func restructureMessage(ctx promptContext) string {
+	info := ctx.restructure
+	typ, scope := detectCommitType(ctx.files)
+	if typ == "" {
+		typ = "refactor"
+	}
+	prefix := conventionalPrefix(typ, scope, ctx.files)
+
+	if info.kind == restructureRemoval {
+		return fmt.Sprintf("%s: remove %s", prefix, pathsTarget(info.deleted))
+	}
+
+	from := make([]string, len(info.renamed))
+	to := make([]string, len(info.renamed))
+	for i, r := range info.renamed {
+		from[i], to[i] = r.from, r.to
+	}
+	if len(info.renamed) == 1 {
+		return fmt.Sprintf("%s: move %s to %s", prefix, from[0], to[0])
+	}
+	fromDir, toDir := commonDir(from), commonDir(to)
+	if fromDir != "" && toDir != "" && fromDir != toDir {
+		return fmt.Sprintf("%s: move %s to %s", prefix, fromDir, toDir)
+	}
+	return fmt.Sprintf("%s: move %d files", prefix, len(info.renamed))
+}
+
+// pathsTarget 用共同的目录概括一组路径，没有共同目录时列出少量路径或只给出数量
+func pathsTarget(paths []string) string {
+	if len(paths) == 1 {
+		return paths[0]
+	}
+	if dir := commonDir(paths); dir != "" {
+		return dir
+	}
+	if len(paths) <= 3 {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%d files", len(paths))
+}
+
+// commonDir 返回一组路径共同所在的目录，在仓库根目录时返回空
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	common := strings.Split(path.Dir(paths[0]), "/")
+	for _, p := range paths[1:] {
+		parts := strings.Split(path.Dir(p), "/")
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	dir := strings.Join(common, "/")
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// configIssue 配置校验发现的问题
+type configIssue struct {
+	line    int
+	key     string
+	message string
+}
+
+// configError 配置文件校验错误，包含文件路径和所有问题
+type configError struct {
+	path   string
+	issues []configIssue
+}
+
+// newConfigError 按行号排序问题并创建错误
+func newConfigError(path string, issues []configIssue) *configError {
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].line < issues[j].line
+	})
+	return &configError{path: path, issues: issues}
+}
+
+func (e *configError) Error() string {
+	lines := make([]string, len(e.issues))
+	for i, issue := range e.issues {
+		if issue.key == "" {
+			lines[i] = fmt.Sprintf("%s:%d: %s", e.path, issue.line, issue.message)
+		} else {
+			lines[i] = fmt.Sprintf("%s:%d: %s: %s", e.path, issue.line, issue.key, issue.message)
+		}
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
+
+// schemaValidator 按结构体定义逐个 token 校验 JSON，记录每个键所在的行
+type schemaValidator struct {
+	data   []byte
+	dec    *json.Decoder
+	issues []configIssue
+	lines  map[string]int
+}
+
+// validateConfigData 校验配置文件内容，typ 为配置对应的结构体类型
+//
+// 键名和类型根据结构体的 json 标签推导，新增配置项无需修改校验逻辑。
+// 返回的 syntaxOK 为 false 时表示 JSON 本身无法解析。
+func validateConfigData(data []byte, typ reflect.Type) (issues []configIssue, lines map[string]int, syntaxOK bool) {
+	v := &schemaValidator{
+		data:  data,
+		dec:   json.NewDecoder(bytes.NewReader(data)),
+		lines: make(map[string]int),
+	}
+	v.dec.UseNumber()
+
+	if err := v.value(typ, "", 1); err != nil {
+		var syntaxErr *json.SyntaxError
+		line := v.line()
+		if errors.As(err, &syntaxErr) {
+			line = lineAt(data, syntaxErr.Offset)
+		}
+		v.issues = append(v.issues, configIssue{line: line, message: tr("schema.syntax", err)})
+		return v.issues, v.lines, false
+	}
+
+	return v.issues, v.lines, true
+}
+
+// lineAt 计算字节偏移所在的行号
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+func (v *schemaValidator) line() int {
+	return lineAt(v.data, v.dec.InputOffset())
+}
+
+func (v *schemaValidator) add(line int, key, message string) {
+	v.issues = append(v.issues, configIssue{line: line, key: key, message: message})
+}
+
+// value 校验一个值，返回 JSON 语法错误
+func (v *schemaValidator) value(typ reflect.Type, path string, line int) error {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	tok, err := v.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+
+	expected := schemaTypeName(typ)
+	actual := tokenTypeName(tok)
+
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Map:
+		if actual != "object" {
+			v.add(line, path, tr("schema.wrong_type", expected, actual))
+			return v.skip(tok)
+		}
+		return v.object(typ, path)
+
+	case reflect.Slice:
+		if actual != "array" {
+			v.add(line, path, tr("schema.wrong_type", expected, actual))
+			return v.skip(tok)
+		}
+		for i := 0; v.dec.More(); i++ {
+			if err := v.value(typ.Elem(), fmt.Sprintf("%s[%d]", path, i), v.line()); err != nil {
+				return err
+			}
+		}
+		_, err := v.dec.Token()
+		return err
+
+	case reflect.Interface:
+		return v.skip(tok)
+	}
+
+	if actual != expected && !(expected == "number" && actual == "integer") {
+		v.add(line, path, tr("schema.wrong_type", expected, actual))
+		return v.skip(tok)
+	}
+	return nil
+}
+
+// object 校验对象的每个键
+func (v *schemaValidator) object(typ reflect.Type, path string) error {
+	fields := jsonFields(typ)
+
+	for v.dec.More() {
+		tok, err := v.dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		line := v.line()
+		v.lines[keyPath] = line
+
+		var fieldType reflect.Type
+		if typ.Kind() == reflect.Map {
+			fieldType = typ.Elem()
+		} else if t, ok := fields[key]; ok {
+			fieldType = t
+		} else {
+			if key != "$schema" {
+				v.add(line, keyPath, tr("schema.unknown_key"))
+			}
+			next, err := v.dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := v.skip(next); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := v.value(fieldType, keyPath, line); err != nil {
+			return err
+		}
+	}
+
+	_, err := v.dec.Token()
+	return err
+}
+
+// skip 跳过已读取首个 token 的值
+func (v *schemaValidator) skip(tok json.Token) error {
+	delim, ok := tok.(json.Delim)
+	if !ok || (delim != '{' && delim != '[') {
+		return nil
+	}
+
+	for depth := 1; depth > 0; {
+		tok, err := v.dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFields 返回结构体 json 标签到字段类型的映射
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	if typ.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// schemaTypeName 返回 Go 类型对应的 JSON 类型名
+func schemaTypeName(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return "any"
+}
+
+// tokenTypeName 返回 JSON token 的类型名
+func tokenTypeName(tok json.Token) string {
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			return "object"
+		}
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(t.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	}
+	return "null"
+}
+
+// isHTTPURL 判断是否为带主机名的 http(s) URL
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validateConfigValues 校验配置项的取值范围
+func validateConfigValues(c *Config, lines map[string]int) []configIssue {
+	var issues []configIssue
+	add := func(key, message string) {
+		issues = append(issues, configIssue{line: lines[key], key: key, message: message})
+	}
+
+	if _, ok := lines["openai_endpoint"]; ok && !isHTTPURL(c.OpenAIEndpoint) {
+		add("openai_endpoint", tr("schema.invalid_url"))
+	}
+	if c.ProxyURL != "" && c.ProxyURL != proxyDirect {
+		if _, err := parseProxyURL(c.ProxyURL, "", ""); err != nil {
+			add("proxy_url", err.Error())
+		}
+	}
+	if c.Temperature < 0 || c.Temperature > 2 {
+		add("temperature", tr("schema.temperature_range"))
+	}
+	if c.TopP != nil && (*c.TopP < 0 || *c.TopP > 1) {
+		add("top_p", tr("schema.top_p_range"))
+	}
+	if c.ResponseContentPath != "" {
+		if _, err := parseResponsePath(c.ResponseContentPath); err != nil {
+			add("response_content_path", err.Error())
+		}
+	}
+	issues = append(issues, validatePromptCache("prompt_cache", c.PromptCache, lines)...)
+	issues = append(issues, validateCommitAs("commit_as", c.CommitAs, lines)...)
+	issues = append(issues, validateEndpoints("", c.OpenAIEndpoints, c.EndpointStrategy, lines)...)
+	issues = append(issues, validateCapabilities(c.Capabilities, lines)...)
+
+	for key, value := range map[string]int{
+		"max_tokens":              c.MaxTokens,
+		"new_file_head_lines":     c.NewFileHeadLines,
+		"max_diff_chars":          c.MaxDiffChars,
+		"max_file_diff_chars":     c.MaxFileDiffChars,
+		"max_parallel":            c.MaxParallel,
+		"refine_passes":           c.RefinePasses,
+		"warn_large_commit_files": c.WarnLargeCommitFiles,
+		"timeout":                 c.Timeout,
+		"retries":                 c.Retries,
+		"retry_delay":             c.RetryDelay,
+	} {
+		if value < 0 {
+			add(key, tr("schema.negative"))
+		}
+	}
+
+	for i, endpoint := range c.AllowedEndpoints {
+		if strings.TrimSpace(endpoint) == "" {
+			issues = append(issues, configIssue{line: lines["allowed_endpoints"], key: fmt.Sprintf("allowed_endpoints[%d]", i), message: tr("schema.empty_entry")})
+		}
+	}
+
+	issues = append(issues, validateScopeSource(c.ScopeSource, lines)...)
+	issues = append(issues, validateOriginReference(c.OriginReference, lines)...)
+	issues = append(issues, validateSensitiveFiles(c.SensitiveFiles, lines)...)
+	issues = append(issues, validateEmojiConfig(c.EmojiMap, c.DisallowedEmojis, lines)...)
+	issues = append(issues, validateSubjectStyle(c.SubjectCase, c.Tense, lines)...)
+	issues = append(issues, validateTrailers(c.Trailers, lines)...)
+	if c.SpellCheck != "" && c.SpellCheck != spellCheckWarn && c.SpellCheck != spellCheckFix {
+		add("spell_check", tr("schema.invalid_choice", spellCheckWarn+", "+spellCheckFix))
+	}
+	if _, ok := vcsByName(c.VCS); c.VCS != "" && !ok {
+		add("vcs", tr("schema.invalid_choice", vcsGit+", "+vcsJujutsu+", "+vcsMercurial))
+	}
+
+	issues = append(issues, validateAuthProviders(c, lines)...)
+
+	if _, ok := c.Profiles[c.Profile]; c.Profile != "" && !ok {
+		add("profile", tr("schema.unknown_profile", c.Profile))
+	}
+	for name, p := range c.Profiles {
+		issues = append(issues, validateProfile("profiles."+name, p, lines)...)
+	}
+
+	if c.UILang != "" && !strings.HasPrefix(c.UILang, uiLangEnglish) && !strings.HasPrefix(c.UILang, uiLangChinese) {
+		add("ui_lang", tr("schema.invalid_choice", uiLangEnglish+", "+uiLangChinese))
+	}
+
+	return issues
+}
+
+// validateProfile 校验服务商配置的取值范围
+func validateProfile(prefix string, p Profile, lines map[string]int) []configIssue {
+	var issues []configIssue
+	add := func(key, message string) {
+		key = prefix + "." + key
+		issues = append(issues, configIssue{line: lines[key], key: key, message: message})
+	}
+
+	if p.OpenAIEndpoint != "" && !isHTTPURL(p.OpenAIEndpoint) {
+		add("openai_endpoint", tr("schema.invalid_url"))
+	}
+	if p.ProxyURL != "" && p.ProxyURL != proxyDirect {
+		if _, err := parseProxyURL(p.ProxyURL, "", ""); err != nil {
+			add("proxy_url", err.Error())
+		}
+	}
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		add("temperature", tr("schema.temperature_range"))
+	}
+	if p.TopP != nil && (*p.TopP < 0 || *p.TopP > 1) {
+		add("top_p", tr("schema.top_p_range"))
+	}
+	if p.ResponseContentPath != "" {
+		if _, err := parseResponsePath(p.ResponseContentPath); err != nil {
+			add("response_content_path", err.Error())
+		}
+	}
+	issues = append(issues, validatePromptCache(prefix+".prompt_cache", p.PromptCache, lines)...)
+	issues = append(issues, validateCommitAs(prefix+".commit_as", p.CommitAs, lines)...)
+	issues = append(issues, validateEndpoints(prefix+".", p.OpenAIEndpoints, p.EndpointStrategy, lines)...)
+	for key, value := range map[string]int{
+		"max_tokens":  p.MaxTokens,
+		"timeout":     p.Timeout,
+		"retry_delay": p.RetryDelay,
+	} {
+		if value < 0 {
+			add(key, tr("schema.negative"))
+		}
+	}
+	if p.Retries != nil && *p.Retries < 0 {
+		add("retries", tr("schema.negative"))
+	}
+
+	return issues
+}
+
+// validateScopeSource 校验 scope_source 的取值，用户配置和仓库级配置共用
+func validateScopeSource(source string, lines map[string]int) []configIssue {
+	if source == "" || source == scopeSourceDirectory || source == scopeSourceCodeowners {
+		return nil
+	}
+	return []configIssue{{line: lines["scope_source"], key: "scope_source", message: tr("schema.invalid_choice", scopeSourceDirectory+", "+scopeSourceCodeowners)}}
+}
+
+// checkConfigFile 校验用户配置文件，返回解析后的配置
+func checkConfigFile(configPath string, data []byte) (Config, error) {
+	var c Config
+	issues, lines, syntaxOK := validateConfigData(data, reflect.TypeOf(Config{}))
+	if syntaxOK {
+		// 类型错误已经记录，其余配置项仍然检查取值
+		json.Unmarshal(data, &c)
+		issues = append(issues, validateConfigValues(&c, lines)...)
+	}
+	if len(issues) > 0 {
+		return c, newConfigError(configPath, issues)
+	}
+	return c, nil
+}
+
+// checkRepoConfigFile 校验仓库级配置文件，返回解析后的配置
+func checkRepoConfigFile(configPath string, data []byte) (RepoConfig, error) {
+	var c RepoConfig
+	issues, lines, syntaxOK := validateConfigData(data, reflect.TypeOf(RepoConfig{}))
+	if syntaxOK {
+		json.Unmarshal(data, &c)
+		issues = append(issues, validateScopeSource(c.ScopeSource, lines)...)
+		issues = append(issues, validateOriginReference(c.OriginReference, lines)...)
+		issues = append(issues, validateSensitiveFiles(c.SensitiveFiles, lines)...)
+		issues = append(issues, validateEmojiConfig(c.EmojiMap, c.DisallowedEmojis, lines)...)
+		issues = append(issues, validateSubjectStyle(c.SubjectCase, c.Tense, lines)...)
+		issues = append(issues, validateTrailers(c.Trailers, lines)...)
+		issues = append(issues, validateLintConfig(c.Lint, lines)...)
+		if c.WarnLargeCommitFiles != nil && *c.WarnLargeCommitFiles < 0 {
+			issues = append(issues, configIssue{line: lines["warn_large_commit_files"], key: "warn_large_commit_files", message: tr("schema.negative")})
+		}
+	}
+	if len(issues) > 0 {
+		return c, newConfigError(configPath, issues)
+	}
+	return c, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	scopeSourceDirectory  = "directory"
+	scopeSourceCodeowners = "codeowners"
+)
+
+// codeownersLocations CODEOWNERS 文件的查找位置，与 GitHub 的查找顺序一致
+var codeownersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// genericDirs 不适合作为 scope 的通用顶层目录
+var genericDirs = map[string]bool{
+	"src": true, "lib": true, "pkg": true, "internal": true, "app": true, "cmd": true, "packages": true,
+}
+
+// codeownersRule CODEOWNERS 中的一条规则
+type codeownersRule struct {
+	pattern string
+	owners  []string
+}
+
+// scopeHint 根据改动文件推导 Conventional Commits scope 提示
+func scopeHint(files []string) string {
+	if config.ScopeSource == "" && len(config.ScopeMap) == 0 {
+		return ""
+	}
+
+	scopes := inferScopes(files)
+	if len(scopes) == 0 {
+		return ""
+	}
+	if len(scopes) == 1 {
+		return fmt.Sprintf("If the commit message uses a Conventional Commits scope, use the scope %q.", scopes[0])
+	}
+
+	return fmt.Sprintf("The change spans these scopes: %s. If the commit message uses a Conventional Commits scope, pick from them, preferring %q.", strings.Join(scopes, ", "), scopes[0])
+}
+
+// inferScopes 推导改动文件所属的 scope，按涉及的文件数从多到少排序
+func inferScopes(files []string) []string {
+	var rules []codeownersRule
+	if config.ScopeSource == scopeSourceCodeowners {
+		rules = loadCodeowners()
+	}
+
+	counts := make(map[string]int)
+	for _, file := range files {
+		scope := scopeFromMap(file)
+		if scope == "" && rules != nil {
+			scope = scopeFromCodeowners(rules, file)
+		}
+		if scope == "" && (config.ScopeSource == scopeSourceDirectory || config.ScopeSource == scopeSourceCodeowners) {
+			scope = scopeFromDirectory(file)
+		}
+		if scope != "" {
+			counts[scope]++
+		}
+	}
+
+	scopes := make([]string, 0, len(counts))
+	for scope := range counts {
+		scopes = append(scopes, scope)
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if counts[scopes[i]] != counts[scopes[j]] {
+			return counts[scopes[i]] > counts[scopes[j]]
+		}
+		return scopes[i] < scopes[j]
+	})
+
+	return scopes
+}
+
+// scopeFromMap 根据 scope_map 中的路径映射查找 scope，最长的模式优先
+//
+// 长度相同的模式按字典序选择，结果不受 map 遍历顺序影响。
+func scopeFromMap(file string) string {
+	patterns := make([]string, 0, len(config.ScopeMap))
+	for pattern := range config.ScopeMap {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	for _, pattern := range patterns {
+		if matchPathPattern(pattern, file) {
+			return config.ScopeMap[pattern]
+		}
+	}
+	return ""
+}
+
+// scopeFromDirectory 根据目录名推导 scope
+func scopeFromDirectory(file string) string {
+	parts := strings.Split(filepath.ToSlash(file), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	if genericDirs[parts[0]] && len(parts) > 2 {
+		return parts[1]
+	}
+	return parts[0]
+}
+
+// loadCodeowners 读取仓库中的 CODEOWNERS 文件
+func loadCodeowners() []codeownersRule {
+	root, err := currentVCS.root()
+	if err != nil {
+		return nil
+	}
+
+	for _, location := range codeownersLocations {
+		content, err := os.ReadFile(filepath.Join(root, location))
+		if err != nil {
+			continue
+		}
+
+		var rules []codeownersRule
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fields := strings.Fields(line)
+			rules = append(rules, codeownersRule{pattern: fields[0], owners: fields[1:]})
+		}
+		return rules
+	}
+
+	return nil
+}
+
+// scopeFromCodeowners 根据 CODEOWNERS 推导 scope，与 GitHub 一样最后匹配的规则生效
+func scopeFromCodeowners(rules []codeownersRule, file string) string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := rules[i]
+		if !matchPathPattern(rule.pattern, file) {
+			continue
+		}
+		if len(rule.owners) == 0 {
+			return ""
+		}
+		return ownerScope(rule.owners[0])
+	}
+	return ""
+}
+
+// ownerScope 将所有者映射为 scope，例如 @org/payments -> payments
+func ownerScope(owner string) string {
+	if scope, ok := config.OwnerScopes[owner]; ok {
+		return scope
+	}
+
+	name := strings.TrimPrefix(owner, "@")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	// 邮箱形式的所有者不适合作为 scope
+	if strings.Contains(name, "@") {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultSensitiveFiles 默认禁止自动暂存和提交的文件，以 ! 开头的模式表示例外
+var defaultSensitiveFiles = []string{
+	".env",
+	".env.*",
+	"!.env.example",
+	"!.env.sample",
+	"!.env.template",
+	"*.pem",
+	"*.key",
+	"*.p12",
+	"*.pfx",
+	"*.jks",
+	"*.keystore",
+	"id_rsa",
+	"id_dsa",
+	"id_ecdsa",
+	"id_ed25519",
+	".npmrc",
+	".pypirc",
+	".netrc",
+	".git-credentials",
+	"**/.aws/credentials",
+	"*.tfstate",
+}
+
+// sensitivePatterns 返回生效的敏感文件模式：默认列表之后依次是仓库级配置和用户配置中的 sensitive_files
+//
+// 仓库级配置随仓库克隆而来，只能增加需要保护的文件，其中以 ! 开头的例外会被忽略，
+// 否则一个提交了 "!.env" 的仓库就能关闭密钥检查。例外只能在用户配置中设置。
+func sensitivePatterns() []string {
+	patterns := append([]string{}, defaultSensitiveFiles...)
+	for _, pattern := range repoConfig.SensitiveFiles {
+		if !strings.HasPrefix(pattern, "!") {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return append(patterns, config.SensitiveFiles...)
+}
+
+// isSensitiveFile 判断文件是否匹配敏感文件模式，与 gitignore 相同，后面的模式优先
+func isSensitiveFile(file string, patterns []string) bool {
+	sensitive := false
+	for _, pattern := range patterns {
+		if allow, ok := strings.CutPrefix(pattern, "!"); ok {
+			if matchPathPattern(allow, file) {
+				sensitive = false
+			}
+		} else if matchPathPattern(pattern, file) {
+			sensitive = true
+		}
+	}
+	return sensitive
+}
+
+// pendingFiles 返回本次会被提交的新增或修改的文件（相对仓库根目录）
+//
+// 包括已暂存的文件，stage 为 true 时还包括 paths 中（未指定时与 stageAll 相同）会被暂存的文件。
+// 删除的文件不在其中，从仓库中删除敏感文件不应被阻止。
+func pendingFiles(paths []string, stage bool) []string {
+	output := runGitCommand("diff", "--cached", "--name-only", "--diff-filter=d")
+
+	if stage {
+		scope := paths
+		if len(scope) == 0 && os.Getenv("GIT_WORK_TREE") == "" {
+			scope = []string{"."}
+		}
+		withScope := func(args ...string) []string {
+			if len(scope) == 0 {
+				return args
+			}
+			return append(append(args, "--"), scope...)
+		}
+		output += runGitCommand(withScope("diff", "--name-only", "--diff-filter=d")...) +
+			runGitCommand(withScope("ls-files", "--others", "--exclude-standard", "--full-name")...)
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		file := strings.TrimSpace(line)
+		if file != "" && !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// checkSensitiveFiles 在暂存前检查是否有敏感文件会被提交，有时列出文件并退出
+//
+// aicommit 会自动暂存所有更改，比手动使用 git 更容易误提交密钥，因此需要通过 --allow-sensitive 明确允许。
+func checkSensitiveFiles(files []string, allow bool) {
+	if allow {
+		return
+	}
+
+	patterns := sensitivePatterns()
+	var blocked []string
+	for _, file := range files {
+		if isSensitiveFile(file, patterns) {
+			blocked = append(blocked, file)
+		}
+	}
+	if len(blocked) == 0 {
+		return
+	}
+
+	message := tr("sensitive.blocked") + "\n  " + strings.Join(blocked, "\n  ") + "\n" + tr("sensitive.hint")
+	failWith(&codedError{code: "sensitive.blocked", message: message})
+}
+
+// validateSensitiveFiles 校验 sensitive_files 中的模式，用户配置和仓库级配置共用
+func validateSensitiveFiles(patterns []string, lines map[string]int) []configIssue {
+	var issues []configIssue
+	for i, pattern := range patterns {
+		if strings.TrimSpace(strings.TrimPrefix(pattern, "!")) == "" {
+			issues = append(issues, configIssue{line: lines["sensitive_files"], key: fmt.Sprintf("sensitive_files[%d]", i), message: tr("schema.empty_entry")})
+		}
+	}
+	return issues
+}
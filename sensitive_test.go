@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestIsSensitiveFile(t *testing.T) {
+	tests := []struct {
+		file     string
+		patterns []string
+		want     bool
+	}{
+		{".env", defaultSensitiveFiles, true},
+		{"config/.env", defaultSensitiveFiles, true},
+		{".env.production", defaultSensitiveFiles, true},
+		{".env.example", defaultSensitiveFiles, false},
+		{"certs/server.pem", defaultSensitiveFiles, true},
+		{"home/.aws/credentials", defaultSensitiveFiles, true},
+		{"docs/env.md", defaultSensitiveFiles, false},
+		{"main.go", defaultSensitiveFiles, false},
+		// 后面的模式优先
+		{"test/fixtures/dummy.pem", []string{"*.pem", "!test/fixtures/dummy.pem"}, false},
+		{"test/fixtures/dummy.pem", []string{"!test/fixtures/dummy.pem", "*.pem"}, true},
+		{"a.secret", []string{"*.secret", "!*.secret", "a.secret"}, true},
+		// 例外只对匹配的文件生效
+		{"other.pem", []string{"*.pem", "!test/fixtures/dummy.pem"}, true},
+	}
+	for _, tt := range tests {
+		if got := isSensitiveFile(tt.file, tt.patterns); got != tt.want {
+			t.Errorf("isSensitiveFile(%q, %q) = %v, want %v", tt.file, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestSensitivePatternsIgnoreRepoExceptions(t *testing.T) {
+	savedConfig, savedRepo := config, repoConfig
+	defer func() { config, repoConfig = savedConfig, savedRepo }()
+
+	tests := []struct {
+		name string
+		user []string
+		repo []string
+		file string
+		want bool
+	}{
+		{"repo cannot unblock .env", nil, []string{"!.env"}, ".env", true},
+		{"repo cannot unblock keys", nil, []string{"!*.pem"}, "server.pem", true},
+		{"repo can add patterns", nil, []string{"*.secret"}, "db.secret", true},
+		{"user can unblock", []string{"!test/dummy.pem"}, nil, "test/dummy.pem", false},
+		{"user exception wins over repo pattern", []string{"!db.secret"}, []string{"*.secret"}, "db.secret", false},
+	}
+	for _, tt := range tests {
+		config.SensitiveFiles = tt.user
+		repoConfig.SensitiveFiles = tt.repo
+		if got := isSensitiveFile(tt.file, sensitivePatterns()); got != tt.want {
+			t.Errorf("%s: isSensitiveFile(%q) = %v, want %v", tt.name, tt.file, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	sequencerCherryPick = "cherry-pick"
+	sequencerRevert     = "revert"
+
+	originReferenceLine    = "line"
+	originReferenceTrailer = "trailer"
+	originReferenceNone    = "none"
+)
+
+// sequencerState 正在进行的 cherry-pick 或 revert
+type sequencerState struct {
+	kind    string
+	sha     string
+	message string
+}
+
+// subject 原提交的标题
+func (s *sequencerState) subject() string {
+	return messageSubject(s.message)
+}
+
+// detectSequencer 检测是否处于 cherry-pick 或 revert 过程中（如解决冲突后），不是时返回 nil
+func detectSequencer() *sequencerState {
+	for kind, ref := range map[string]string{
+		sequencerCherryPick: "CHERRY_PICK_HEAD",
+		sequencerRevert:     "REVERT_HEAD",
+	} {
+		sha, err := gitOutput("rev-parse", "-q", "--verify", ref)
+		if err != nil {
+			continue
+		}
+		sha = strings.TrimSpace(sha)
+		message, err := gitOutput("log", "-1", "--format=%B", sha)
+		if err != nil {
+			continue
+		}
+		return &sequencerState{kind: kind, sha: sha, message: strings.TrimSpace(message)}
+	}
+	return nil
+}
+
+// sequencerHint 提供原提交的信息，让模型生成引用原提交的提交信息
+func sequencerHint(s *sequencerState) string {
+	if s == nil {
+		return ""
+	}
+
+	if s.kind == sequencerRevert {
+		return fmt.Sprintf("This commit reverts commit %s, whose message was:\n\n%s\n\nThe subject must be Revert \"%s\" (or use the \"revert\" type if the repository uses Conventional Commits). In the body, explain what is being undone based on the original message and the diff.", shortSHA(s.sha), s.message, s.subject())
+	}
+	return fmt.Sprintf("This commit is a cherry-pick of commit %s, whose message was:\n\n%s\n\nKeep the intent and wording of the original message. Only adjust it where the diff shows the change had to be adapted, for example to resolve conflicts, and mention such adaptations in the body.", shortSHA(s.sha), s.message)
+}
+
+// originReference 提交信息引用原提交的方式
+func originReference() string {
+	if config.OriginReference != "" {
+		return config.OriginReference
+	}
+	return originReferenceLine
+}
+
+// appendOriginReference 在提交信息末尾引用原提交
+//
+// 默认与 git cherry-pick -x 和 git revert 的格式相同，设置 origin_reference 为 trailer 时使用 trailer。
+func appendOriginReference(message string, s *sequencerState) string {
+	if s == nil || strings.Contains(message, s.sha) {
+		return message
+	}
+
+	switch originReference() {
+	case originReferenceNone:
+		return message
+	case originReferenceTrailer:
+		if s.kind == sequencerRevert {
+			return appendTrailer(message, "Reverts", s.sha)
+		}
+		return appendTrailer(message, "Cherry-picked-from", s.sha)
+	}
+
+	if s.kind == sequencerRevert {
+		return strings.TrimRight(message, "\n") + "\n\nThis reverts commit " + s.sha + "."
+	}
+	return strings.TrimRight(message, "\n") + "\n\n(cherry picked from commit " + s.sha + ")"
+}
+
+// validateOriginReference 校验 origin_reference 的取值，用户配置和仓库级配置共用
+func validateOriginReference(value string, lines map[string]int) []configIssue {
+	switch value {
+	case "", originReferenceLine, originReferenceTrailer, originReferenceNone:
+		return nil
+	}
+	return []configIssue{{line: lines["origin_reference"], key: "origin_reference", message: tr("schema.invalid_choice", originReferenceLine+", "+originReferenceTrailer+", "+originReferenceNone)}}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	symlinkMode   = "120000"
+	submoduleMode = "160000"
+
+	// maxSubmoduleLog 子模块更新时最多列出的提交数
+	maxSubmoduleLog = 20
+)
+
+// specialChange 符号链接或子模块改动解析结果
+type specialChange struct {
+	mode     string
+	oldValue string
+	newValue string
+	created  bool
+	deleted  bool
+}
+
+// parseSpecialChange 解析文件差异，不是符号链接或子模块时返回 false
+func parseSpecialChange(text string) (specialChange, bool) {
+	var c specialChange
+	var oldLines, newLines []string
+	inHunk := false
+
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case !inHunk && strings.HasPrefix(line, "new file mode "):
+			c.mode = strings.TrimPrefix(line, "new file mode ")
+			c.created = true
+		case !inHunk && strings.HasPrefix(line, "deleted file mode "):
+			c.mode = strings.TrimPrefix(line, "deleted file mode ")
+			c.deleted = true
+		case !inHunk && strings.HasPrefix(line, "index ") && c.mode == "":
+			if fields := strings.Fields(line); len(fields) == 3 {
+				c.mode = fields[2]
+			}
+		case inHunk && strings.HasPrefix(line, "-"):
+			oldLines = append(oldLines, strings.TrimPrefix(line, "-"))
+		case inHunk && strings.HasPrefix(line, "+"):
+			newLines = append(newLines, strings.TrimPrefix(line, "+"))
+		}
+	}
+
+	if c.mode != symlinkMode && c.mode != submoduleMode {
+		return c, false
+	}
+
+	c.oldValue = strings.Join(oldLines, "\n")
+	c.newValue = strings.Join(newLines, "\n")
+	return c, true
+}
+
+// describeSpecialFile 将符号链接和子模块的差异转为易读的描述，其他文件返回 false
+//
+// 子模块的原始差异只有 "Subproject commit <sha>"，模型无法从中看出改动内容，
+// 子模块已检出时附上两个版本之间的提交列表。
+func describeSpecialFile(f fileDiff) (string, bool) {
+	c, ok := parseSpecialChange(f.text)
+	if !ok {
+		return "", false
+	}
+
+	if c.mode == symlinkMode {
+		switch {
+		case c.created:
+			return fmt.Sprintf("Symlink %s created, pointing to %s", f.path, c.newValue), true
+		case c.deleted:
+			return fmt.Sprintf("Symlink %s removed (it pointed to %s)", f.path, c.oldValue), true
+		default:
+			return fmt.Sprintf("Symlink %s retargeted from %s to %s", f.path, c.oldValue, c.newValue), true
+		}
+	}
+
+	oldSHA, _ := submoduleCommit(c.oldValue)
+	newSHA, dirty := submoduleCommit(c.newValue)
+	var b strings.Builder
+	switch {
+	case c.created:
+		fmt.Fprintf(&b, "Submodule %s added at %s", f.path, shortSHA(newSHA))
+	case c.deleted:
+		fmt.Fprintf(&b, "Submodule %s removed (it was at %s)", f.path, shortSHA(oldSHA))
+	case oldSHA == newSHA:
+		fmt.Fprintf(&b, "Submodule %s has uncommitted changes", f.path)
+	default:
+		fmt.Fprintf(&b, "Submodule %s updated from %s to %s", f.path, shortSHA(oldSHA), shortSHA(newSHA))
+		b.WriteString(submoduleLog(f.path, oldSHA, newSHA))
+	}
+	if dirty && oldSHA != newSHA {
+		b.WriteString("\n  (the submodule also has uncommitted changes)")
+	}
+
+	return b.String(), true
+}
+
+// submoduleCommit 从 "Subproject commit <sha>[-dirty]" 中取出提交哈希
+func submoduleCommit(value string) (string, bool) {
+	sha := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "Subproject commit "))
+	if strings.HasSuffix(sha, "-dirty") {
+		return strings.TrimSuffix(sha, "-dirty"), true
+	}
+	return sha, false
+}
+
+// shortSHA 返回缩写的提交哈希
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// submoduleGitOutput 在子模块中运行 git 命令
+//
+// 需要去掉指向外层仓库的 GIT_DIR 等环境变量，否则 git 仍会操作外层仓库。
+func submoduleGitOutput(path string, args ...string) (string, error) {
+	root, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("git", gitCompatArgs(append([]string{"-C", filepath.Join(strings.TrimSpace(root), path)}, args...))...)
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if !containsString(gitPathEnvVars, name) {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// containsString 判断字符串是否在列表中
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// submoduleLog 获取子模块两个版本之间的提交列表，子模块未检出或缺少提交时返回空
+func submoduleLog(path, oldSHA, newSHA string) string {
+	limit := fmt.Sprintf("-n%d", maxSubmoduleLog+1)
+	added, err := submoduleGitOutput(path, "log", "--oneline", "--no-decorate", limit, oldSHA+".."+newSHA)
+	if err != nil {
+		return ""
+	}
+	removed, _ := submoduleGitOutput(path, "log", "--oneline", "--no-decorate", limit, newSHA+".."+oldSHA)
+
+	var b strings.Builder
+	writeLog := func(title, output string) {
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if output == "" || lines[0] == "" {
+			return
+		}
+		fmt.Fprintf(&b, "\n  %s:", title)
+		for i, line := range lines {
+			if i == maxSubmoduleLog {
+				b.WriteString("\n    ...")
+				break
+			}
+			b.WriteString("\n    * " + line)
+		}
+	}
+	writeLog("new commits", added)
+	writeLog("commits rolled back", removed)
+
+	return b.String()
+}
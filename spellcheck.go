@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	spellCheckWarn = "warn"
+	spellCheckFix  = "fix"
+)
+
+// spellCheckers 支持的拼写检查程序，都使用 ispell 兼容的管道模式 (-a)
+var spellCheckers = []string{"hunspell", "aspell"}
+
+// hunspellDictionaries 常用语言对应的 hunspell 词典名，aspell 直接使用语言代码
+var hunspellDictionaries = map[string]string{
+	"en": "en_US",
+	"de": "de_DE",
+	"fr": "fr_FR",
+	"es": "es_ES",
+	"it": "it_IT",
+	"pt": "pt_BR",
+	"nl": "nl_NL",
+	"ru": "ru_RU",
+	"pl": "pl_PL",
+	"sv": "sv_SE",
+}
+
+// languageCodes 语言名称对应的语言代码，default_lang 也可以写成语言名称
+var languageCodes = map[string]string{
+	"english":    "en",
+	"german":     "de",
+	"french":     "fr",
+	"spanish":    "es",
+	"italian":    "it",
+	"portuguese": "pt",
+	"dutch":      "nl",
+	"russian":    "ru",
+	"polish":     "pl",
+	"swedish":    "sv",
+}
+
+// spellWordPattern 拆分单词时去掉的首尾标点
+var spellWordPattern = regexp.MustCompile(`^[\pP]*(.*?)[\pP]*$`)
+
+// errNoSpellChecker 没有安装拼写检查程序
+var errNoSpellChecker = errors.New("no spell checker found")
+
+// misspelling 拼写错误的单词及检查程序给出的建议
+type misspelling struct {
+	word        string
+	suggestions []string
+}
+
+// findSpellChecker 返回已安装的拼写检查程序
+func findSpellChecker() (string, error) {
+	for _, name := range spellCheckers {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", errNoSpellChecker
+}
+
+// spellDictionaries 根据提交信息的语言返回要使用的词典，中文、日文等不以空格分词的语言没有词典
+func spellDictionaries(checker, langs string) []string {
+	if config.SpellDictionary != "" {
+		return []string{config.SpellDictionary}
+	}
+
+	var dicts []string
+	for _, lang := range strings.Split(langs, ",") {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if code, ok := languageCodes[lang]; ok {
+			lang = code
+		}
+		code, region, _ := strings.Cut(strings.ReplaceAll(lang, "-", "_"), "_")
+		dict, ok := hunspellDictionaries[code]
+		if !ok {
+			continue
+		}
+		switch {
+		case checker != "hunspell":
+			dict = code
+		case region != "":
+			dict = code + "_" + strings.ToUpper(region)
+		}
+		dicts = append(dicts, dict)
+	}
+	return dicts
+}
+
+// spellCandidates 取出提交信息中需要检查的单词
+//
+// 代码标识符、路径、缩写、带数字或连字符的词以及在差异中出现过的词都不检查，
+// 这些词多半是函数名、配置项或项目术语，词典中没有也不是拼写错误。
+func spellCandidates(message, diff string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, token := range strings.Fields(message) {
+		if strings.ContainsAny(strings.Trim(token, `.,;:!?"'()`), "`/\\_.@#=<>()[]{}:-0123456789") {
+			continue
+		}
+		word := spellWordPattern.FindStringSubmatch(token)[1]
+		if len([]rune(word)) < 3 || seen[word] || strings.Contains(diff, word) {
+			continue
+		}
+
+		plain, upper := true, 0
+		for i, r := range word {
+			if !unicode.IsLetter(r) || unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+				plain = false
+				break
+			}
+			if i > 0 && unicode.IsUpper(r) {
+				upper++
+			}
+		}
+		if !plain || upper > 0 {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// checkSpelling 用词典检查单词，返回拼写错误的单词
+//
+// 每个单词单独一行并加上 "^" 前缀，避免被当作管道模式的命令；每行的结果为
+// "*"（正确）、"& <词> <数量> <位置>: <建议>, ..." 或 "# <词> <位置>"（没有建议），之后是一个空行。
+func checkSpelling(checker, dict string, words []string) ([]misspelling, error) {
+	args := []string{"-a", "-d", dict}
+	if checker == "aspell" {
+		args = []string{"-a", "--lang=" + dict}
+	}
+
+	var input bytes.Buffer
+	for _, w := range words {
+		input.WriteString("^" + w + "\n")
+	}
+	cmd := exec.Command(checker, args...)
+	cmd.Stdin = &input
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []misspelling
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case strings.HasPrefix(line, "& "):
+			head, suggestions, _ := strings.Cut(line, ": ")
+			fields := strings.Fields(head)
+			m := misspelling{word: fields[1]}
+			for _, s := range strings.Split(suggestions, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					m.suggestions = append(m.suggestions, s)
+				}
+			}
+			result = append(result, m)
+		case strings.HasPrefix(line, "# "):
+			if fields := strings.Fields(line); len(fields) > 1 {
+				result = append(result, misspelling{word: fields[1]})
+			}
+		}
+	}
+	return result, nil
+}
+
+// spellCheckMessage 检查生成的提交信息的拼写，fix 模式下用第一个建议替换拼错的单词
+//
+// 配置了多种语言时，只有所有词典都认为拼错的单词才算错误。没有安装检查程序或
+// 没有适用的词典时只输出警告，不影响提交。
+func spellCheckMessage(message, lang, diff string) string {
+	if config.SpellCheck == "" {
+		return message
+	}
+
+	checker, err := findSpellChecker()
+	if err != nil {
+		fmt.Fprintln(stdout, tr("spell.unavailable"))
+		return message
+	}
+	dicts := spellDictionaries(checker, lang)
+	words := spellCandidates(message, diff)
+	if len(dicts) == 0 || len(words) == 0 {
+		return message
+	}
+
+	var misspelled []misspelling
+	for i, dict := range dicts {
+		result, err := checkSpelling(checker, dict, words)
+		if err != nil {
+			fmt.Fprintln(stdout, tr("spell.failed", checker, dict, err))
+			return message
+		}
+		if i == 0 {
+			misspelled = result
+			continue
+		}
+		// 只保留每个词典都认为拼错的单词
+		wrong := make(map[string]bool, len(result))
+		for _, m := range result {
+			wrong[m.word] = true
+		}
+		kept := misspelled[:0]
+		for _, m := range misspelled {
+			if wrong[m.word] {
+				kept = append(kept, m)
+			}
+		}
+		misspelled = kept
+	}
+
+	for _, m := range misspelled {
+		if len(m.suggestions) == 0 {
+			fmt.Fprintln(stdout, tr("spell.flagged", m.word))
+			continue
+		}
+		if config.SpellCheck != spellCheckFix {
+			fmt.Fprintln(stdout, tr("spell.suggested", m.word, strings.Join(m.suggestions, ", ")))
+			continue
+		}
+		pattern := regexp.MustCompile(`(^|[^\pL])` + regexp.QuoteMeta(m.word) + `($|[^\pL])`)
+		message = pattern.ReplaceAllString(message, "${1}"+strings.ReplaceAll(m.suggestions[0], "$", "$$")+"${2}")
+		fmt.Fprintln(stdout, tr("spell.fixed", m.word, m.suggestions[0]))
+	}
+	return message
+}
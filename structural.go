@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeaderPattern 解析 @@ -a,b +c,d @@ 中的行号范围
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// goDecl Go 文件中的一个顶层声明
+type goDecl struct {
+	name  string
+	text  string
+	start int
+	end   int
+}
+
+// declChange 声明级别的改动
+type declChange struct {
+	kind string
+	decl goDecl
+	old  bool
+}
+
+// parseGoDecls 解析 Go 源码中的顶层声明，分组的 var/const/type 按每一项拆开
+func parseGoDecls(src []byte) ([]goDecl, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var decls []goDecl
+	add := func(name string, node ast.Node, doc *ast.CommentGroup) {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		decls = append(decls, goDecl{
+			name:  name,
+			text:  string(src[fset.Position(start).Offset:fset.Position(node.End()).Offset]),
+			start: fset.Position(start).Line,
+			end:   fset.Position(node.End()).Line,
+		})
+	}
+
+	for _, d := range file.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			add(funcDeclName(d), d, d.Doc)
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				add("imports", d, d.Doc)
+				continue
+			}
+			for _, spec := range d.Specs {
+				// 未分组的声明包含关键字和注释，分组的声明按每一项取范围
+				var node ast.Node = d
+				doc := d.Doc
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if d.Lparen.IsValid() {
+						node, doc = s, s.Doc
+					}
+					add("type "+s.Name.Name, node, doc)
+				case *ast.ValueSpec:
+					if d.Lparen.IsValid() {
+						node, doc = s, s.Doc
+					}
+					names := make([]string, len(s.Names))
+					for i, n := range s.Names {
+						names[i] = n.Name
+					}
+					add(d.Tok.String()+" "+strings.Join(names, ", "), node, doc)
+				}
+			}
+		}
+	}
+	return decls, nil
+}
+
+// funcDeclName 返回函数或方法的名称，方法带上接收者类型，如 method (*Server).Start
+func funcDeclName(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return "func " + d.Name.Name
+	}
+	return fmt.Sprintf("method (%s).%s", receiverType(d.Recv.List[0].Type), d.Name.Name)
+}
+
+// receiverType 返回方法接收者的类型名，去掉泛型参数
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "?"
+}
+
+// diffDecls 按名称比较新旧声明，返回按新文件中的顺序排列的改动，删除的声明排在最后
+func diffDecls(oldDecls, newDecls []goDecl) []declChange {
+	oldByName := make(map[string]goDecl, len(oldDecls))
+	for _, d := range oldDecls {
+		oldByName[d.name] = d
+	}
+	newNames := make(map[string]bool, len(newDecls))
+
+	var changes []declChange
+	for _, d := range newDecls {
+		newNames[d.name] = true
+		old, ok := oldByName[d.name]
+		switch {
+		case !ok:
+			changes = append(changes, declChange{kind: "added", decl: d})
+		case old.text != d.text:
+			changes = append(changes, declChange{kind: "modified", decl: d})
+		}
+	}
+	for _, d := range oldDecls {
+		if !newNames[d.name] {
+			changes = append(changes, declChange{kind: "removed", decl: d, old: true})
+		}
+	}
+	return changes
+}
+
+// hunkRanges 解析块头中旧文件和新文件的起止行
+func hunkRanges(hunk string) (oldStart, oldEnd, newStart, newEnd int, ok bool) {
+	match := hunkHeaderPattern.FindStringSubmatch(hunk)
+	if match == nil {
+		return 0, 0, 0, 0, false
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	oldStart, _ = strconv.Atoi(match[1])
+	newStart, _ = strconv.Atoi(match[3])
+	return oldStart, oldStart + count(match[2]) - 1, newStart, newStart + count(match[4]) - 1, true
+}
+
+// renamedFrom 返回重命名前的路径，没有重命名时返回当前路径
+func renamedFrom(f fileDiff) string {
+	for _, line := range strings.Split(f.text, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			break
+		}
+		if strings.HasPrefix(line, "rename from ") {
+			return strings.TrimPrefix(line, "rename from ")
+		}
+	}
+	return f.path
+}
+
+// structuralGoDiff 将修改过的 Go 文件的差异按声明分组，不是 Go 文件或无法解析时返回 false
+//
+// 先列出新增、删除和修改的函数、方法、类型等声明，再把每个块归到它所在的声明下，
+// 重构时模型可以看出哪些函数被移动或改名，而不是只看到零散的增删行。
+func structuralGoDiff(f fileDiff) (string, bool) {
+	if !strings.HasSuffix(f.path, ".go") || f.newFile || strings.Contains(f.text, "\ndeleted file mode ") {
+		return "", false
+	}
+
+	oldSrc, err := gitOutput("show", "HEAD:"+renamedFrom(f))
+	if err != nil {
+		return "", false
+	}
+	newSrc, err := gitOutput("show", ":"+f.path)
+	if err != nil {
+		return "", false
+	}
+	oldDecls, err := parseGoDecls([]byte(oldSrc))
+	if err != nil {
+		return "", false
+	}
+	newDecls, err := parseGoDecls([]byte(newSrc))
+	if err != nil {
+		return "", false
+	}
+
+	changes := diffDecls(oldDecls, newDecls)
+	header, hunks := splitHunks(f.text)
+
+	var b strings.Builder
+	b.WriteString(header)
+	b.WriteString("\nStructural diff (Go declarations):")
+	for _, kind := range []string{"added", "removed", "modified"} {
+		var names []string
+		for _, c := range changes {
+			if c.kind == kind {
+				names = append(names, c.decl.name)
+			}
+		}
+		if len(names) > 0 {
+			fmt.Fprintf(&b, "\n  %s: %s", kind, strings.Join(names, "; "))
+		}
+	}
+
+	// 把每个块归到第一个与之重叠的改动声明下
+	grouped := make([][]string, len(changes))
+	var other []string
+	for _, hunk := range hunks {
+		oldStart, oldEnd, newStart, newEnd, ok := hunkRanges(hunk)
+		owner := -1
+		for i, c := range changes {
+			start, end := newStart, newEnd
+			if c.old {
+				start, end = oldStart, oldEnd
+			}
+			if ok && c.decl.start <= end && start <= c.decl.end {
+				owner = i
+				break
+			}
+		}
+		if owner < 0 {
+			other = append(other, hunk)
+		} else {
+			grouped[owner] = append(grouped[owner], hunk)
+		}
+	}
+
+	for i, c := range changes {
+		if len(grouped[i]) > 0 {
+			fmt.Fprintf(&b, "\n## %s %s\n%s", c.kind, c.decl.name, strings.Join(grouped[i], "\n"))
+		}
+	}
+	if len(other) > 0 {
+		fmt.Fprintf(&b, "\n## other changes (comments, formatting)\n%s", strings.Join(other, "\n"))
+	}
+
+	return b.String(), true
+}
+
+// structuralFilesHint 告知模型哪些文件的差异已按声明分组
+func structuralFilesHint(structural []string) string {
+	if len(structural) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("The diffs of these Go files start with a list of added, removed and modified declarations, and their hunks are grouped under the declaration they belong to: %s. Use the declaration names to describe refactors such as moved, renamed or split functions.", strings.Join(structural, ", "))
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	subjectCaseSentence = "sentence"
+	subjectCaseLower    = "lower"
+	subjectCaseTitle    = "title"
+
+	tenseImperative = "imperative"
+	tensePast       = "past"
+)
+
+// pastTenseVerbs 提交标题中常见动词的祈使式和过去式，不规则动词和需要双写辅音的动词也在其中
+var pastTenseVerbs = map[string]string{
+	"add": "added", "adjust": "adjusted", "allow": "allowed", "apply": "applied", "avoid": "avoided",
+	"begin": "began", "bring": "brought", "build": "built", "bump": "bumped", "cache": "cached",
+	"change": "changed", "check": "checked", "choose": "chose", "clean": "cleaned", "configure": "configured",
+	"convert": "converted", "copy": "copied", "correct": "corrected", "create": "created", "cut": "cut",
+	"delete": "deleted", "deprecate": "deprecated", "disable": "disabled", "document": "documented", "downgrade": "downgraded",
+	"drop": "dropped", "enable": "enabled", "ensure": "ensured", "expose": "exposed", "extract": "extracted",
+	"find": "found", "fix": "fixed", "handle": "handled", "hide": "hid", "ignore": "ignored",
+	"implement": "implemented", "improve": "improved", "include": "included", "inline": "inlined", "introduce": "introduced",
+	"keep": "kept", "limit": "limited", "log": "logged", "make": "made", "merge": "merged",
+	"migrate": "migrated", "modify": "modified", "move": "moved", "optimize": "optimized", "pass": "passed",
+	"prevent": "prevented", "print": "printed", "put": "put", "read": "read", "reduce": "reduced",
+	"refactor": "refactored", "release": "released", "remove": "removed", "rename": "renamed", "reorder": "reordered",
+	"reorganize": "reorganized", "replace": "replaced", "reset": "reset", "resolve": "resolved", "restore": "restored",
+	"retry": "retried", "return": "returned", "revert": "reverted", "rewrite": "rewrote", "run": "ran",
+	"send": "sent", "set": "set", "show": "showed", "simplify": "simplified", "skip": "skipped",
+	"sort": "sorted", "specify": "specified", "split": "split", "start": "started", "stop": "stopped",
+	"store": "stored", "support": "supported", "switch": "switched", "test": "tested", "track": "tracked",
+	"trim": "trimmed", "tweak": "tweaked", "unify": "unified", "update": "updated", "upgrade": "upgraded",
+	"use": "used", "validate": "validated", "wrap": "wrapped", "write": "wrote",
+}
+
+// titleSmallWords 标题式大小写中除第一个词外保持小写的虚词
+var titleSmallWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true, "nor": true,
+	"as": true, "at": true, "by": true, "for": true, "from": true, "in": true, "into": true,
+	"of": true, "on": true, "to": true, "vs": true, "via": true, "with": true,
+}
+
+var imperativeVerbs map[string]string
+
+// verbForms 返回动词各种形式到祈使式的映射，包括过去式和第三人称单数（如 adds、fixes）
+func verbForms() map[string]string {
+	if imperativeVerbs != nil {
+		return imperativeVerbs
+	}
+	imperativeVerbs = make(map[string]string)
+	for verb, past := range pastTenseVerbs {
+		imperativeVerbs[verb] = verb
+		imperativeVerbs[past] = verb
+		imperativeVerbs[thirdPerson(verb)] = verb
+	}
+	return imperativeVerbs
+}
+
+// thirdPerson 返回动词的第三人称单数形式
+func thirdPerson(verb string) string {
+	switch {
+	case strings.HasSuffix(verb, "s"), strings.HasSuffix(verb, "x"), strings.HasSuffix(verb, "z"),
+		strings.HasSuffix(verb, "ch"), strings.HasSuffix(verb, "sh"):
+		return verb + "es"
+	case strings.HasSuffix(verb, "y") && len(verb) > 1 && !strings.ContainsRune("aeiou", rune(verb[len(verb)-2])):
+		return verb[:len(verb)-1] + "ies"
+	}
+	return verb + "s"
+}
+
+// isEnglishLang 判断提交信息的语言是否为英文
+func isEnglishLang(lang string) bool {
+	lang = strings.ToLower(lang)
+	return strings.HasPrefix(lang, "en")
+}
+
+// applySubjectStyle 按 subject_case 和 tense 在本地调整英文标题的大小写和时态
+//
+// 只调整标题中 Conventional Commits 前缀和 emoji 之后的描述，正文不变。时态只转换
+// 描述开头的常见动词，不认识的动词保持原样；大小写不改变代码标识符和缩写（如
+// parseConfig、HTTP、v2.1）。其他语言的信息不做调整。
+func applySubjectStyle(message, lang string) string {
+	if config.SubjectCase == "" && config.Tense == "" || message == "" || !isEnglishLang(lang) {
+		return message
+	}
+
+	subject, body, hasBody := strings.Cut(message, "\n")
+	emoji, text := splitLeadingEmoji(subject)
+	prefix := ""
+	if loc := conventionalTypePattern.FindStringIndex(text); loc != nil {
+		prefix, text = text[:loc[1]], text[loc[1]:]
+	}
+
+	words := strings.Split(text, " ")
+	if config.Tense != "" {
+		words[0] = convertTense(words[0], config.Tense)
+	}
+	if config.SubjectCase != "" {
+		words = convertCase(words, config.SubjectCase)
+	}
+
+	subject = prefix + strings.Join(words, " ")
+	if emoji != "" {
+		subject = emoji + " " + subject
+	}
+	if !hasBody {
+		return subject
+	}
+	return subject + "\n" + body
+}
+
+// convertTense 将描述开头的动词转换为指定的时态，保留原来的首字母大小写
+func convertTense(word, tense string) string {
+	verb, ok := verbForms()[strings.ToLower(word)]
+	if !ok {
+		return word
+	}
+	converted := verb
+	if tense == tensePast {
+		converted = pastTenseVerbs[verb]
+	}
+	if first, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(first) {
+		return capitalize(converted)
+	}
+	return converted
+}
+
+// convertCase 按 subject_case 调整描述中各个词的大小写
+//
+// sentence 和 lower 只决定第一个词的首字母；描述是每个词首字母大写的标题式写法时，
+// 其余的词也改为小写。
+func convertCase(words []string, style string) []string {
+	titled := isTitleCased(words)
+	for i, w := range words {
+		if isIdentifier(w) {
+			continue
+		}
+		switch {
+		case style == subjectCaseTitle:
+			if i > 0 && titleSmallWords[strings.ToLower(w)] {
+				words[i] = strings.ToLower(w)
+			} else {
+				words[i] = capitalize(w)
+			}
+		case i == 0 && style == subjectCaseSentence:
+			words[i] = capitalize(w)
+		case i == 0 || titled:
+			words[i] = lowerFirst(w)
+		}
+	}
+	return words
+}
+
+// isTitleCased 判断描述是否每个实词都以大写字母开头，如 "Add New Login Page"
+func isTitleCased(words []string) bool {
+	count := 0
+	for i, w := range words {
+		if i == 0 || isIdentifier(w) || titleSmallWords[strings.ToLower(w)] {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(w)
+		if !unicode.IsUpper(first) {
+			return false
+		}
+		count++
+	}
+	return count > 0
+}
+
+// isIdentifier 判断词是否像代码标识符、缩写、路径或版本号，这些词不改变大小写
+func isIdentifier(word string) bool {
+	letters := 0
+	for i, r := range word {
+		switch {
+		case unicode.IsLetter(r):
+			if i > 0 && unicode.IsUpper(r) {
+				return true
+			}
+			letters++
+		case strings.ContainsRune("`_./\\#@<>(){}[]=*", r), unicode.IsDigit(r):
+			return true
+		}
+	}
+	return letters == 0
+}
+
+// capitalize 将词的首字母改为大写
+func capitalize(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	if size == 0 {
+		return word
+	}
+	return string(unicode.ToUpper(r)) + word[size:]
+}
+
+// lowerFirst 将词的首字母改为小写
+func lowerFirst(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	if size == 0 {
+		return word
+	}
+	return string(unicode.ToLower(r)) + word[size:]
+}
+
+// validateSubjectStyle 校验 subject_case 和 tense 的取值，用户配置和仓库级配置共用
+func validateSubjectStyle(subjectCase, tense string, lines map[string]int) []configIssue {
+	var issues []configIssue
+	switch subjectCase {
+	case "", subjectCaseSentence, subjectCaseLower, subjectCaseTitle:
+	default:
+		issues = append(issues, configIssue{line: lines["subject_case"], key: "subject_case", message: tr("schema.invalid_choice", subjectCaseSentence+", "+subjectCaseLower+", "+subjectCaseTitle)})
+	}
+	switch tense {
+	case "", tenseImperative, tensePast:
+	default:
+		issues = append(issues, configIssue{line: lines["tense"], key: "tense", message: tr("schema.invalid_choice", tenseImperative+", "+tensePast)})
+	}
+	return issues
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestApplySubjectStyle(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+
+	tests := []struct {
+		subjectCase string
+		tense       string
+		lang        string
+		message     string
+		want        string
+	}{
+		// 时态
+		{"", tenseImperative, "en", "feat: added login page", "feat: add login page"},
+		{"", tenseImperative, "en", "fix(api): Fixes timeout", "fix(api): Fix timeout"},
+		{"", tenseImperative, "en", "chore: copies files", "chore: copy files"},
+		{"", tensePast, "en", "fix: stop retrying on 4xx", "fix: stopped retrying on 4xx"},
+		{"", tensePast, "en", "feat: writes cache to disk", "feat: wrote cache to disk"},
+		{"", tensePast, "en", "docs: polish readme", "docs: polish readme"},
+		// 大小写
+		{subjectCaseSentence, "", "en", "feat: add login page", "feat: Add login page"},
+		{subjectCaseLower, "", "en", "feat: Add login page", "feat: add login page"},
+		{subjectCaseLower, "", "en", "feat: Add New Login Page", "feat: add new login page"},
+		{subjectCaseSentence, "", "en", "feat: Add New Login Page", "feat: Add new login page"},
+		{subjectCaseTitle, "", "en", "feat: add support for the new login flow", "feat: Add Support for the New Login Flow"},
+		// 标识符、缩写和版本号保持原样
+		{subjectCaseLower, "", "en", "fix: Handle HTTP errors in parseConfig", "fix: handle HTTP errors in parseConfig"},
+		{subjectCaseTitle, "", "en", "chore: bump go to v1.24", "chore: Bump Go to v1.24"},
+		{subjectCaseLower, "", "en", "Fix `Config` loading", "fix `Config` loading"},
+		// 同时设置，emoji 和正文保留
+		{subjectCaseSentence, tenseImperative, "en", "✨ feat(ui): added dark mode\n\nAdded a toggle.", "✨ feat(ui): Add dark mode\n\nAdded a toggle."},
+		{subjectCaseLower, tensePast, "en_US", "Fix crash on start", "fixed crash on start"},
+		// 其他语言不调整
+		{subjectCaseSentence, tenseImperative, "zh", "feat: 添加登录页面", "feat: 添加登录页面"},
+		{subjectCaseSentence, tensePast, "zh-CN", "feat: add login", "feat: add login"},
+	}
+	for _, tt := range tests {
+		config.SubjectCase, config.Tense = tt.subjectCase, tt.tense
+		if got := applySubjectStyle(tt.message, tt.lang); got != tt.want {
+			t.Errorf("applySubjectStyle(%q) with case=%q tense=%q = %q, want %q", tt.message, tt.subjectCase, tt.tense, got, tt.want)
+		}
+	}
+}
+
+func TestThirdPerson(t *testing.T) {
+	tests := map[string]string{
+		"add":   "adds",
+		"fix":   "fixes",
+		"push":  "pushes",
+		"pass":  "passes",
+		"copy":  "copies",
+		"apply": "applies",
+		"play":  "plays",
+	}
+	for verb, want := range tests {
+		if got := thirdPerson(verb); got != want {
+			t.Errorf("thirdPerson(%q) = %q, want %q", verb, got, want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxDiffChars = 20000
+	defaultMaxParallel  = 4
+)
+
+// diffChunk 分段摘要时的一段差异
+type diffChunk struct {
+	files []string
+	text  string
+}
+
+// maxDiffChars 提示词中差异的最大字符数，超出时分段摘要
+func maxDiffChars() int {
+	if config.MaxDiffChars > 0 {
+		return config.MaxDiffChars
+	}
+	return defaultMaxDiffChars
+}
+
+// maxParallel 分段摘要的最大并发数
+func maxParallel() int {
+	if config.MaxParallel > 0 {
+		return config.MaxParallel
+	}
+	return defaultMaxParallel
+}
+
+// chunkDiff 将文件差异按字符预算分组，单个文件超出预算时截断
+func chunkDiff(files []fileDiff, budget int) []diffChunk {
+	var chunks []diffChunk
+	var current diffChunk
+
+	for _, f := range files {
+		text := truncateHunk(f.text, budget)
+		if current.text != "" && len(current.text)+len(text)+1 > budget {
+			chunks = append(chunks, current)
+			current = diffChunk{}
+		}
+		current.files = append(current.files, f.path)
+		if current.text != "" {
+			current.text += "\n"
+		}
+		current.text += text
+	}
+	if current.text != "" {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// summarizeChunks 并发地为每段差异生成摘要，并输出每段的进度
+//
+// queue 不为 nil 时跳过之前已完成的段，并在每段完成后保存进度。
+func summarizeChunks(chunks []diffChunk, buildPrompt func(diffChunk) string, queue *jobQueue) ([]string, error) {
+	summaries := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+
+	var (
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+		done        int
+		filesDone   int
+		totalTokens int
+	)
+	start := time.Now()
+	sem := make(chan struct{}, maxParallel())
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk diffChunk) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			prompt := buildPrompt(chunk)
+			var result completion
+			var err error
+			if cached, ok := queue.lookup(prompt); ok {
+				result.content = cached
+			} else {
+				result, err = requestChat(prompt)
+				if err == nil {
+					queue.record(prompt, result.content)
+				}
+			}
+			summaries[i], errs[i] = result.content, err
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			filesDone += len(chunk.files)
+			totalTokens += result.usage.TotalTokens
+			eta := time.Duration(0)
+			if done < len(chunks) {
+				eta = time.Since(start) / time.Duration(done) * time.Duration(len(chunks)-done)
+			}
+			fmt.Fprintln(stderr, tr("summary.progress", done, len(chunks), filesDone, totalTokens, eta.Round(time.Second)))
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if errors.Is(err, errDeadlineExceeded) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, newError("summary.failed", i+1, err)
+		}
+	}
+
+	return summaries, nil
+}
+
+// summarizeDiff 差异过大时分段摘要，返回合并后的摘要
+func summarizeDiff(files []fileDiff) (string, error) {
+	chunks := chunkDiff(files, maxDiffChars())
+	fmt.Fprintln(stderr, tr("summary.start", len(chunks), maxParallel()))
+
+	summaries, err := summarizeChunks(chunks, buildChunkSummaryPrompt, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, summary := range summaries {
+		fmt.Fprintf(&b, "Part %d (%s):\n%s\n\n", i+1, strings.Join(chunks[i].files, ", "), summary)
+	}
+	return b.String(), nil
+}
+
+// summarizeCommits 提交的补丁或条目过大时分段摘要，返回合并后的摘要，每段标出包含的提交
+//
+// files 中每项的路径为提交的哈希，queue 不为 nil 时可以从中断处继续。
+func summarizeCommits(files []fileDiff, buildPrompt func(diffChunk) string, queue *jobQueue) (string, error) {
+	chunks := chunkDiff(files, maxDiffChars())
+	fmt.Fprintln(stderr, tr("summary.start", len(chunks), maxParallel()))
+
+	summaries, err := summarizeChunks(chunks, buildPrompt, queue)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, summary := range summaries {
+		fmt.Fprintf(&b, "Part %d (commits %s):\n%s\n\n", i+1, strings.Join(chunks[i].files, ", "), summary)
+	}
+	return b.String(), nil
+}
+
+// buildChunkSummaryPrompt 构建单段差异的摘要提示词
+func buildChunkSummaryPrompt(chunk diffChunk) string {
+	return renderTemplate("summary", promptData{Diff: chunk.text})
+}
+
+// summarizedHint 告知模型差异已被摘要
+func summarizedHint(summarized bool) string {
+	if !summarized {
+		return ""
+	}
+	return "The full diff was too large, so the changes above are summaries of its parts. Base the commit message on these summaries."
+}
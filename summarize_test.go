@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkDiffKeepsValidUTF8(t *testing.T) {
+	// 一整行的中文注释，没有可以截断的换行
+	text := "+// " + strings.Repeat("提交信息", 50)
+	for budget := 10; budget < 40; budget++ {
+		chunks := chunkDiff([]fileDiff{{path: "main.go", text: text}}, budget)
+		if len(chunks) != 1 {
+			t.Fatalf("budget %d: got %d chunks, want 1", budget, len(chunks))
+		}
+		if !utf8.ValidString(chunks[0].text) {
+			t.Errorf("budget %d: chunk is not valid UTF-8: %q", budget, chunks[0].text)
+		}
+	}
+}
+
+func TestTruncateHunk(t *testing.T) {
+	tests := []struct {
+		hunk   string
+		budget int
+		want   string
+	}{
+		{"short", 10, "short"},
+		// 优先在换行处截断
+		{"line one\nline two\nline three", 12, "line one"},
+		// 没有换行时在字符边界处截断，"中" 占 3 个字节
+		{"中文中文", 4, "中"},
+		{"中文中文", 6, "中文"},
+		{"abc", 3, "abc"},
+	}
+	for _, tt := range tests {
+		got := truncateHunk(tt.hunk, tt.budget)
+		if got != tt.hunk {
+			got = strings.TrimSuffix(got, "\n"+tr("summary.file_truncated"))
+		}
+		if got != tt.want {
+			t.Errorf("truncateHunk(%q, %d) = %q, want %q", tt.hunk, tt.budget, got, tt.want)
+		}
+	}
+}
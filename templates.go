@@ -0,0 +1,255 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	templatesDirName = "templates"
+	templateExt      = ".tmpl"
+
+	// templateRecentCommits 模板中 .RecentCommits 包含的提交数
+	templateRecentCommits = 10
+)
+
+// defaultTemplates 内置的提示词模板，可被 ~/.aicommit/templates/ 中的同名文件覆盖
+//
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// templateFuncs 模板中可用的函数
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"trim": strings.TrimSpace,
+}
+
+// promptData 提交信息相关模板中可用的变量
+type promptData struct {
+	Diff          string
+	Stat          string
+	Lang          string
+	Notes         string
+	Branch        string
+	RecentCommits []string
+	Files         []string
+	Hints         []string
+	Gitmoji       string
+	StyleGuide    string
+	Draft         string
+	Edits         []editExample
+	Rejected      []feedbackEntry
+}
+
+// fixupPromptData fixup 模板中可用的变量
+type fixupPromptData struct {
+	Diff          string
+	Lang          string
+	Kind          string
+	Target        string
+	TargetSubject string
+}
+
+// learnPromptData learn 模板中可用的变量
+type learnPromptData struct {
+	Samples []string
+}
+
+// releaseNotesPromptData release_notes 模板中可用的变量
+type releaseNotesPromptData struct {
+	Entries  string
+	From     string
+	To       string
+	Audience string
+	Format   string
+	Lang     string
+}
+
+// templateCommit 模板中的一个提交
+type templateCommit struct {
+	Hash    string
+	Subject string
+	Author  string
+}
+
+// digestPromptData digest 模板中可用的变量
+type digestPromptData struct {
+	Commits []templateCommit
+	Authors []string
+	Changes string
+	Since   string
+	Format  string
+	Lang    string
+}
+
+// pushPromptData push_check 模板中可用的变量
+type pushPromptData struct {
+	Branch  string
+	Commits []templateCommit
+	Changes string
+	Lang    string
+}
+
+// lintPromptCommit lint 模板中的一个提交
+type lintPromptCommit struct {
+	Hash    string
+	Message string
+	Stat    string
+}
+
+// lintPromptData lint 模板中可用的变量
+type lintPromptData struct {
+	StyleGuide string
+	Lang       string
+	Commits    []lintPromptCommit
+}
+
+// reviewPromptComment review_summary 模板中的一条评审意见
+type reviewPromptComment struct {
+	Author string
+	Body   string
+}
+
+// reviewPromptThread review_summary 模板中的一个评审讨论
+type reviewPromptThread struct {
+	Location string
+	Comments []reviewPromptComment
+}
+
+// reviewPromptData review_summary 模板中可用的变量
+type reviewPromptData struct {
+	Title   string
+	Lang    string
+	Threads []reviewPromptThread
+	Reviews []reviewPromptComment
+}
+
+// newPromptData 根据上下文构建模板变量
+func newPromptData(ctx promptContext) promptData {
+	return promptData{
+		Diff:          ctx.diff,
+		Stat:          ctx.stat,
+		Lang:          ctx.lang,
+		Notes:         strings.TrimSpace(ctx.notes),
+		Branch:        currentBranch(),
+		RecentCommits: getRecentSubjects(templateRecentCommits),
+		Files:         ctx.files,
+		Hints:         promptHints(ctx),
+		Gitmoji:       gitmojiHint(),
+		StyleGuide:    repoConfig.StyleGuide,
+		Edits:         recentEdits(),
+		Rejected:      rejectedExamples(),
+	}
+}
+
+// currentBranch 返回当前分支名，分离头指针时返回空
+func currentBranch() string {
+	output, err := gitOutput("symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
+
+// templatesDir 返回用户模板目录
+func templatesDir() (string, error) {
+	configPath, err := getConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), templatesDirName), nil
+}
+
+// templateSource 返回模板内容及其来源，用户目录中有同名文件时优先使用
+func templateSource(name string) (string, string) {
+	if dir, err := templatesDir(); err == nil {
+		path := filepath.Join(dir, name+templateExt)
+		data, err := readConfigFile(path)
+		if err == nil {
+			return string(data), path
+		}
+		if !os.IsNotExist(err) {
+			fail("template.read_failed", path, err)
+		}
+	}
+
+	data, err := defaultTemplates.ReadFile(templatesDirName + "/" + name + templateExt)
+	if err != nil {
+		fail("template.read_failed", name+templateExt, err)
+	}
+	return string(data), "builtin:" + name + templateExt
+}
+
+// renderTemplate 渲染提示词模板，模板有误时退出并指出出错的文件
+func renderTemplate(name string, data interface{}) string {
+	text, source := templateSource(name)
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		fail("template.invalid", source, err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		fail("template.invalid", source, err)
+	}
+	return b.String()
+}
+
+// defaultTemplateNames 返回内置模板的名称
+func defaultTemplateNames() []string {
+	entries, _ := defaultTemplates.ReadDir(templatesDirName)
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), templateExt))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runConfigTemplates 列出提示词模板及其来源，--export 时将内置模板写入用户模板目录以便修改
+func runConfigTemplates(args []string) {
+	export := false
+	for _, arg := range args {
+		switch arg {
+		case "--help", "-h":
+			printHelp()
+			os.Exit(0)
+		case "--export":
+			export = true
+		default:
+			failUsage("arg.unknown", arg)
+		}
+	}
+
+	dir, err := templatesDir()
+	if err != nil {
+		fail("err.load_config", err)
+	}
+	if export {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fail("template.write_failed", dir, err)
+		}
+	}
+
+	for _, name := range defaultTemplateNames() {
+		path := filepath.Join(dir, name+templateExt)
+		if _, err := os.Stat(path); err == nil {
+			fmt.Fprintln(stdout, tr("template.overridden", name, path))
+			continue
+		}
+		if !export {
+			fmt.Fprintln(stdout, tr("template.builtin", name))
+			continue
+		}
+		data, _ := defaultTemplates.ReadFile(templatesDirName + "/" + name + templateExt)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			fail("template.write_failed", path, err)
+		}
+		fmt.Fprintln(stdout, tr("template.exported", name, path))
+	}
+}
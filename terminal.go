@@ -0,0 +1,21 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// isTerminal 判断文件是否连接到终端
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// enableVirtualTerminal 确保终端能解析转义序列，类 Unix 系统的终端都支持
+func enableVirtualTerminal(f *os.File) bool {
+	return true
+}
+
+// openTerminalInput 打开控制终端用于读取输入，标准输入被钩子等占用时使用
+func openTerminalInput() (*os.File, error) {
+	return os.Open("/dev/tty")
+}
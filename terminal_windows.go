@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableVirtualTerminalProcessing 控制台模式中开启转义序列解析的标志
+const enableVirtualTerminalProcessing = 0x0004
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// isTerminal 判断文件是否连接到控制台
+//
+// Windows 中重定向到 NUL 的文件同样是字符设备，需要通过 GetConsoleMode 判断。
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
+
+// enableVirtualTerminal 为控制台开启转义序列解析
+//
+// Windows Terminal 和 Windows 10 以后的控制台支持该模式；旧版控制台开启失败时，
+// 只有在 ConEmu、ANSICON 等会自行解析转义序列的环境中才返回 true。
+func enableVirtualTerminal(f *os.File) bool {
+	handle := syscall.Handle(f.Fd())
+	var mode uint32
+	if syscall.GetConsoleMode(handle, &mode) != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	if ok, _, _ := procSetConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing)); ok != 0 {
+		return true
+	}
+	return os.Getenv("WT_SESSION") != "" || os.Getenv("ConEmuANSI") == "ON" || os.Getenv("ANSICON") != ""
+}
+
+// openTerminalInput 打开控制台用于读取输入，标准输入被钩子等占用时使用
+func openTerminalInput() (*os.File, error) {
+	return os.Open("CONIN$")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// testDirs 存放测试代码的常见目录名
+var testDirs = []string{"test", "tests", "__tests__", "spec", "specs", "testdata", "e2e"}
+
+// testFileSuffixes 常见测试文件名后缀
+var testFileSuffixes = []string{
+	"_test.go", "_test.py", "_test.rb", "_spec.rb", "_test.exs", "_test.dart",
+	"Test.java", "Tests.java", "Test.kt", "Tests.cs", "Test.cs", "Tests.swift",
+}
+
+// isTestFile 根据路径判断是否为测试文件
+func isTestFile(file string) bool {
+	base := path.Base(file)
+	if strings.HasPrefix(base, "test_") && strings.HasSuffix(base, ".py") {
+		return true
+	}
+	for _, suffix := range testFileSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+	// foo.test.ts、foo.spec.js 等
+	if parts := strings.Split(base, "."); len(parts) > 2 {
+		for _, part := range parts[1 : len(parts)-1] {
+			if part == "test" || part == "spec" {
+				return true
+			}
+		}
+	}
+
+	for _, dir := range strings.Split(path.Dir(file), "/") {
+		for _, testDir := range testDirs {
+			if dir == testDir {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// testPlanHint 开启 include_test_plan 且修改了测试文件时，要求生成 Testing: 小节
+func testPlanHint(diffs []fileDiff) string {
+	if !config.IncludeTestPlan {
+		return ""
+	}
+
+	var tests []string
+	for _, d := range diffs {
+		if isTestFile(d.path) {
+			tests = append(tests, d.path)
+		}
+	}
+	if len(tests) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("The change modifies these test files: %s. End the commit message body with a section that starts with a line \"Testing:\" followed by bullet points summarizing which behaviors the changed tests now cover. Base this section only on the test changes.", strings.Join(tests, ", "))
+}
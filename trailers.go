@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	diffHashTrailerKey = "Diff-Hash"
+	diffHashLength     = 16
+)
+
+// stagedDiffHash 计算暂存区差异的短哈希
+//
+// 使用 --no-color --no-ext-diff 避免用户配置影响输出，提交后可以用
+// `git diff --no-color --no-ext-diff <rev>^ <rev> | sha256sum` 重新计算并比对。
+func stagedDiffHash() string {
+	sum := sha256.Sum256([]byte(runGitCommand(withPathspecs("diff", "--cached", "--no-color", "--no-ext-diff")...)))
+	return hex.EncodeToString(sum[:])[:diffHashLength]
+}
+
+// appendTrailer 在提交信息末尾追加一个 trailer
+func appendTrailer(message, key, value string) string {
+	return strings.TrimRight(message, "\n") + "\n\n" + key + ": " + value
+}
+
+var (
+	// trailerKeyPattern trailer 的名称只能由字母、数字和 - 组成
+	trailerKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+
+	// jiraTicketPattern 分支名中 Jira 风格的工单号，如 feature/PROJ-123-login
+	jiraTicketPattern = regexp.MustCompile(`[A-Z][A-Z0-9]+-[0-9]+`)
+
+	// issueNumberPattern 分支名中单独的一段数字，如 fix/123-crash 或 issue-45
+	issueNumberPattern = regexp.MustCompile(`(?:^|[/_-])#?([0-9]+)(?:[/_-]|$)`)
+)
+
+// trailerData trailers 配置的值中可以使用的变量
+type trailerData struct {
+	Branch string
+	Ticket string
+}
+
+// branchTicket 从分支名中提取工单号，优先匹配 Jira 风格的工单号，其次是单独的一段数字
+func branchTicket(branch string) string {
+	if ticket := jiraTicketPattern.FindString(branch); ticket != "" {
+		return ticket
+	}
+	if m := issueNumberPattern.FindStringSubmatch(branch); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// renderTrailerValue 渲染 trailers 配置中的值，值按 text/template 语法解析
+func renderTrailerValue(value string, data trailerData) (string, error) {
+	tmpl, err := template.New("trailer").Funcs(templateFuncs).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(b.String()), " "), nil
+}
+
+// applyConfigTrailers 按 trailers 配置在提交信息末尾追加 trailer
+//
+// 渲染结果为空的 trailer 不追加，例如不在工单分支上时 `Refs: {{.Ticket}}`。git 仓库中
+// 通过 git interpret-trailers 追加，与信息中已有的 trailer 合并到同一段落，值相同的
+// trailer 不重复追加。
+func applyConfigTrailers(message string) string {
+	if len(config.Trailers) == 0 {
+		return message
+	}
+	branch := currentBranch()
+	data := trailerData{Branch: branch, Ticket: branchTicket(branch)}
+
+	keys := make([]string, 0, len(config.Trailers))
+	for key := range config.Trailers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var trailers []string
+	for _, key := range keys {
+		value, err := renderTrailerValue(config.Trailers[key], data)
+		if err != nil {
+			fail("trailer.invalid", key, err)
+		}
+		if value != "" {
+			trailers = append(trailers, key+": "+value)
+		}
+	}
+	if len(trailers) == 0 {
+		return message
+	}
+
+	if currentVCS.name() == vcsGit {
+		interpreted, err := interpretTrailers(message, trailers)
+		if err == nil {
+			return interpreted
+		}
+		fmt.Fprintln(stderr, tr("trailer.interpret_failed", err))
+	}
+	for _, trailer := range trailers {
+		key, value, _ := strings.Cut(trailer, ": ")
+		message = appendFooter(message, key, value)
+	}
+	return message
+}
+
+// interpretTrailers 运行 git interpret-trailers 追加 trailer
+func interpretTrailers(message string, trailers []string) (string, error) {
+	args := []string{"interpret-trailers", "--if-exists", "addIfDifferent"}
+	for _, trailer := range trailers {
+		args = append(args, "--trailer", trailer)
+	}
+	cmd := exec.Command("git", gitCompatArgs(args)...)
+	cmd.Stdin = strings.NewReader(strings.TrimRight(message, "\n") + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// validateTrailers 校验 trailers 配置的名称和值
+func validateTrailers(trailers map[string]string, lines map[string]int) []configIssue {
+	keys := make([]string, 0, len(trailers))
+	for key := range trailers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var issues []configIssue
+	for _, key := range keys {
+		value := trailers[key]
+		path := "trailers." + key
+		switch {
+		case !trailerKeyPattern.MatchString(key):
+			issues = append(issues, configIssue{line: lines[path], key: path, message: tr("schema.invalid_trailer_key")})
+		case strings.TrimSpace(value) == "":
+			issues = append(issues, configIssue{line: lines[path], key: path, message: tr("schema.empty_entry")})
+		default:
+			// 用空的变量渲染一次，字段名拼写错误等问题在加载配置时就能发现
+			if _, err := renderTrailerValue(value, trailerData{}); err != nil {
+				issues = append(issues, configIssue{line: lines[path], key: path, message: tr("schema.invalid_template", err)})
+			}
+		}
+	}
+	return issues
+}
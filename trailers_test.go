@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestBranchTicket(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"feature/PROJ-123-login", "PROJ-123"},
+		{"PROJ-7", "PROJ-7"},
+		{"bugfix/AB2-45_crash", "AB2-45"},
+		{"fix/123-crash", "123"},
+		{"issue-45", "45"},
+		{"fix/#88", "88"},
+		{"hotfix_9_payments", "9"},
+		// Jira 风格的工单号优先
+		{"12-PROJ-99-refactor", "PROJ-99"},
+		// 不是单独一段的数字不算工单号
+		{"feature/oauth2", ""},
+		{"release/v1.24", ""},
+		{"proj-123-lowercase", "123"},
+		{"main", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := branchTicket(tt.branch); got != tt.want {
+			t.Errorf("branchTicket(%q) = %q, want %q", tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestRenderTrailerValue(t *testing.T) {
+	data := trailerData{Branch: "feature/PROJ-1-x", Ticket: "PROJ-1"}
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"{{.Ticket}}", "PROJ-1", false},
+		{"https://jira.example.com/browse/{{.Ticket}}", "https://jira.example.com/browse/PROJ-1", false},
+		{"{{if .Ticket}} {{.Ticket}}\n{{end}}", "PROJ-1", false},
+		{"static", "static", false},
+		{"{{.Missing}}", "", true},
+		{"{{.Ticket", "", true},
+	}
+	for _, tt := range tests {
+		got, err := renderTrailerValue(tt.value, data)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("renderTrailerValue(%q) = %q, %v, want %q, wantErr %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxDiffLineChars 差异中单行的最大字符数，超过时视为压缩后的代码或生成的数据
+const maxDiffLineChars = 2000
+
+// sanitizeFileDiff 将非 UTF-8 的行和超长的行替换为占位符，没有这类行时返回 false
+//
+// Latin-1、GBK 等编码的文件以及压缩后的 JS/CSS、内联的 base64 数据，git 仍当作文本输出，
+// 原样放入提示词会产生乱码，一行就可能占满整个预算。所有内容行都被替换时只保留统计信息。
+func sanitizeFileDiff(f fileDiff) (string, bool) {
+	lines := strings.Split(f.text, "\n")
+	replaced, content := 0, 0
+	inHunk := false
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+			lines[i] = strings.ToValidUTF8(line, "�")
+			continue
+		}
+		if !inHunk || line == "" || !strings.ContainsAny(line[:1], "+- ") {
+			// 头部的路径等也可能不是 UTF-8
+			lines[i] = strings.ToValidUTF8(line, "�")
+			continue
+		}
+
+		content++
+		prefix, body := line[:1], line[1:]
+		switch {
+		case !utf8.ValidString(body):
+			lines[i] = prefix + fmt.Sprintf("[non-UTF-8 line of %d bytes omitted]", len(body))
+		case utf8.RuneCountInString(body) > maxDiffLineChars:
+			lines[i] = prefix + fmt.Sprintf("[line of %d characters omitted, likely minified or generated]", utf8.RuneCountInString(body))
+		default:
+			continue
+		}
+		replaced++
+	}
+
+	if replaced == 0 {
+		return "", false
+	}
+	if replaced < content {
+		return strings.Join(lines, "\n"), true
+	}
+
+	header, hunks := splitHunks(strings.Join(lines, "\n"))
+	added, deleted := diffStat(hunks)
+	return fmt.Sprintf("%s\n%s | %d insertions(+), %d deletions(-), content skipped (not UTF-8 text or minified)", header, f.path, added, deleted), true
+}
+
+// skippedFilesHint 告知模型哪些文件的内容被替换为占位符
+func skippedFilesHint(skipped []string) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("Some lines of these files are not valid UTF-8 text or are extremely long (for example minified bundles or embedded data), so they were replaced with placeholders: %s. Describe these files at a high level instead of guessing their content.", strings.Join(skipped, ", "))
+}
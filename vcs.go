@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	vcsGit       = "git"
+	vcsJujutsu   = "jj"
+	vcsMercurial = "hg"
+)
+
+// vcs 版本控制系统，生成提交信息的流程通过它读取改动并提交
+//
+// 除了 git，还支持 jujutsu (jj) 和 Mercurial (hg)，差异统一使用 git 格式，
+// 之后的差异处理和提示词构建与 git 仓库完全相同。
+type vcs interface {
+	name() string
+	// root 返回工作区的根目录
+	root() (string, error)
+	// metaDir 返回保存本地状态的目录，如 .git、.jj、.hg
+	metaDir() (string, error)
+	// pendingFiles 返回将要提交的文件，不包括删除的文件；stage 为 true 时包括将被 stage 加入的文件
+	pendingFiles(stage bool) []string
+	// stage 将改动加入本次提交，git 中为暂存
+	stage()
+	// refresh 刷新工作区状态
+	refresh()
+	// diff 返回将要提交的改动，git 格式
+	diff() string
+	// diffStat 返回改动的统计信息，如 git diff --stat --summary，无法获取时返回空
+	diffStat() string
+	// changedFiles 返回有改动的文件
+	changedFiles() []string
+	// recentSubjects 返回最近 n 个提交的标题
+	recentSubjects(n int) []string
+	// commit 提交改动，edit 为 true 时先在编辑器中修改信息
+	commit(message string, edit bool)
+	// lastMessage 返回刚才提交的信息
+	lastMessage() string
+}
+
+// currentVCS 当前仓库使用的版本控制系统
+var currentVCS vcs = gitVCS{}
+
+// vcsByName 根据名称返回版本控制系统
+func vcsByName(name string) (vcs, bool) {
+	switch name {
+	case vcsGit:
+		return gitVCS{}, true
+	case vcsJujutsu:
+		return jjVCS{}, true
+	case vcsMercurial:
+		return hgVCS{}, true
+	}
+	return nil, false
+}
+
+// detectVCS 从当前目录向上查找 .jj、.hg 或 .git，使用最近的一个
+//
+// jj 的共存仓库同时有 .jj 和 .git，这时使用 jj，避免绕过 jj 直接修改 git 仓库。
+// 通过 --git-dir 等参数或 GIT_DIR 指定了 git 仓库时总是使用 git。
+func detectVCS() (vcs, bool) {
+	if os.Getenv("GIT_DIR") != "" {
+		return gitVCS{}, true
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, false
+	}
+	for {
+		for _, marker := range []string{".jj", ".hg", ".git"} {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return vcsByName(strings.TrimPrefix(marker, "."))
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, false
+		}
+		dir = parent
+	}
+}
+
+// ensureRepository 检查当前目录是否在受支持的仓库中，并选择对应的版本控制系统
+func ensureRepository() {
+	if v, ok := detectVCS(); ok {
+		currentVCS = v
+		if _, err := v.root(); err == nil {
+			return
+		}
+	}
+
+	cwd, _ := os.Getwd()
+	fail("err.not_repo", cwd)
+}
+
+// applyVCSConfig 配置了 vcs 时使用指定的版本控制系统
+func applyVCSConfig() {
+	if v, ok := vcsByName(config.VCS); ok {
+		currentVCS = v
+	}
+}
+
+// runVCSCommand 运行版本控制系统的命令并返回输出，出错时退出
+func runVCSCommand(env []string, name string, args ...string) string {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		fail("err.vcs", name, err)
+	}
+	return output.String()
+}
+
+// runVCSInteractive 运行需要连接终端的命令，如打开编辑器
+func runVCSInteractive(env []string, name string, args ...string) {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fail("err.vcs", name, err)
+	}
+}
+
+// vcsOutput 运行命令并返回输出，出错时返回错误而不退出
+func vcsOutput(name string, args ...string) (string, error) {
+	output, err := exec.Command(name, args...).Output()
+	return string(output), err
+}
+
+// splitLines 拆分命令输出的行，去掉空行和重复的行
+func splitLines(output string) []string {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// withPaths 在命令参数后加上本次提交限定的路径
+func withPaths(args ...string) []string {
+	return append(args, pathspecs...)
+}
+
+// gitVCS git 仓库
+type gitVCS struct{}
+
+func (gitVCS) name() string { return vcsGit }
+
+func (gitVCS) root() (string, error) {
+	output, err := gitOutput("rev-parse", "--show-toplevel")
+	return strings.TrimSpace(output), err
+}
+
+func (gitVCS) metaDir() (string, error) { return gitCommonDir() }
+
+func (gitVCS) pendingFiles(stage bool) []string { return pendingFiles(pathspecs, stage) }
+
+func (gitVCS) stage() { stageAll() }
+
+func (gitVCS) refresh() { runGitCommand("status") }
+
+func (gitVCS) diff() string { return getGitDiff() }
+
+// diffStat 统计工作目录和暂存区相对 HEAD 的改动，仓库还没有提交时只统计暂存区
+func (gitVCS) diffStat() string {
+	args := []string{"diff", "HEAD", "--stat=" + strconv.Itoa(diffStatWidth), "--summary"}
+	if _, err := gitOutput("rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		args = []string{"diff", "--cached", "--stat=" + strconv.Itoa(diffStatWidth), "--summary"}
+	}
+	output, _ := gitOutput(withPathspecs(args...)...)
+	return output
+}
+
+func (gitVCS) changedFiles() []string { return getChangedFiles() }
+
+func (gitVCS) recentSubjects(n int) []string {
+	output, err := gitOutput("log", "-n", strconv.Itoa(n), "--format=%s")
+	if err != nil {
+		// 仓库还没有提交
+		return nil
+	}
+	return splitLines(output)
+}
+
+func (gitVCS) commit(message string, edit bool) {
+	if edit {
+		commitWithEditor(message)
+		return
+	}
+	commitChanges(message)
+}
+
+func (gitVCS) lastMessage() string {
+	return strings.TrimSpace(runGitCommand("log", "-1", "--format=%B"))
+}
+
+// jjVCS jujutsu 仓库，工作区的改动自动属于当前的变更 @，没有暂存区
+type jjVCS struct{}
+
+func (jjVCS) name() string { return vcsJujutsu }
+
+func (jjVCS) root() (string, error) {
+	output, err := vcsOutput("jj", "root")
+	return strings.TrimSpace(output), err
+}
+
+func (v jjVCS) metaDir() (string, error) {
+	root, err := v.root()
+	return filepath.Join(root, ".jj"), err
+}
+
+func (jjVCS) pendingFiles(stage bool) []string {
+	// --summary 的每行为 "<状态> <路径>"
+	var files []string
+	for _, line := range splitLines(runVCSCommand(nil, "jj", withPaths("diff", "--summary")...)) {
+		status, path, _ := strings.Cut(line, " ")
+		if status != "D" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+func (jjVCS) stage() {}
+
+func (jjVCS) refresh() { runVCSCommand(nil, "jj", "status") }
+
+func (jjVCS) diff() string { return runVCSCommand(nil, "jj", withPaths("diff", "--git")...) }
+
+func (jjVCS) diffStat() string {
+	output, _ := vcsOutput("jj", withPaths("diff", "--stat")...)
+	return output
+}
+
+func (jjVCS) changedFiles() []string {
+	return splitLines(runVCSCommand(nil, "jj", withPaths("diff", "--name-only")...))
+}
+
+func (jjVCS) recentSubjects(n int) []string {
+	output, err := vcsOutput("jj", "log", "-r", "::@-", "--limit", strconv.Itoa(n), "--no-graph", "-T", `description.first_line() ++ "\n"`)
+	if err != nil {
+		return nil
+	}
+	return splitLines(output)
+}
+
+// commit 使用 jj commit 提交，edit 时再用 jj describe 在编辑器中修改刚才的提交
+func (jjVCS) commit(message string, edit bool) {
+	env := jjAuthorEnv()
+	runVCSCommand(env, "jj", withPaths("commit", "-m", message)...)
+	if edit {
+		runVCSInteractive(env, "jj", "describe", "-r", "@-")
+	}
+}
+
+func (jjVCS) lastMessage() string {
+	return strings.TrimSpace(runVCSCommand(nil, "jj", "log", "-r", "@-", "--no-graph", "-T", "description"))
+}
+
+// jjAuthorEnv 配置了 commit_as 时通过 JJ_USER 和 JJ_EMAIL 指定作者
+func jjAuthorEnv() []string {
+	if config.CommitAs == "" {
+		return nil
+	}
+	addr, err := parseCommitAs(config.CommitAs)
+	if err != nil {
+		return nil
+	}
+	return []string{"JJ_USER=" + addr.Name, "JJ_EMAIL=" + addr.Address}
+}
+
+// hgVCS Mercurial 仓库，新文件和删除的文件需要 addremove 后才会提交
+type hgVCS struct{}
+
+func (hgVCS) name() string { return vcsMercurial }
+
+func (hgVCS) root() (string, error) {
+	output, err := vcsOutput("hg", "root")
+	return strings.TrimSpace(output), err
+}
+
+func (v hgVCS) metaDir() (string, error) {
+	root, err := v.root()
+	return filepath.Join(root, ".hg"), err
+}
+
+func (hgVCS) pendingFiles(stage bool) []string {
+	args := []string{"status", "-n", "-m", "-a"}
+	if stage {
+		args = append(args, "-u")
+	}
+	return splitLines(runVCSCommand(nil, "hg", withPaths(args...)...))
+}
+
+func (hgVCS) stage() { runVCSCommand(nil, "hg", withPaths("addremove", "-q")...) }
+
+func (hgVCS) refresh() { runVCSCommand(nil, "hg", "status") }
+
+func (hgVCS) diff() string { return runVCSCommand(nil, "hg", withPaths("diff", "--git")...) }
+
+func (hgVCS) diffStat() string {
+	output, _ := vcsOutput("hg", withPaths("diff", "--stat")...)
+	return output
+}
+
+func (hgVCS) changedFiles() []string {
+	return splitLines(runVCSCommand(nil, "hg", withPaths("status", "-n", "-m", "-a", "-r")...))
+}
+
+func (hgVCS) recentSubjects(n int) []string {
+	output, err := vcsOutput("hg", "log", "-l", strconv.Itoa(n), "--template", "{desc|firstline}\n")
+	if err != nil {
+		return nil
+	}
+	return splitLines(output)
+}
+
+func (hgVCS) commit(message string, edit bool) {
+	args := []string{"commit", "-m", message}
+	if config.CommitAs != "" {
+		args = append(args, "-u", config.CommitAs)
+	}
+	if edit {
+		runVCSInteractive(nil, "hg", withPaths(append(args, "--edit")...)...)
+		return
+	}
+	runVCSCommand(nil, "hg", withPaths(args...)...)
+}
+
+func (hgVCS) lastMessage() string {
+	return strings.TrimSpace(runVCSCommand(nil, "hg", "log", "-r", ".", "--template", "{desc}"))
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ecosystemDir 某个生态中存放第三方依赖或构建产物的目录
+//
+// 只有同一目录下存在对应的清单文件时才排除，例如 build/ 只在有 build.gradle 时
+// 视为 Gradle 的构建产物，避免误排除手写代码所在的同名目录。
+type ecosystemDir struct {
+	ecosystem string
+	dir       string
+	manifests []string
+}
+
+// ecosystemDirs 内置的各生态的依赖和构建产物目录，清单文件支持 *.tf 这样的通配符
+var ecosystemDirs = []ecosystemDir{
+	{"npm", "node_modules", []string{"package.json"}},
+	{"npm", "dist", []string{"package.json"}},
+	{"Bower", "bower_components", []string{"bower.json"}},
+	{"Go modules", "vendor", []string{"go.mod"}},
+	{"Composer", "vendor", []string{"composer.json"}},
+	{"Bundler", "vendor/bundle", []string{"Gemfile"}},
+	{"Cargo", "target", []string{"Cargo.toml"}},
+	{"Maven", "target", []string{"pom.xml"}},
+	{"Gradle", "build", []string{"build.gradle", "build.gradle.kts"}},
+	{"Gradle", ".gradle", []string{"build.gradle", "build.gradle.kts"}},
+	{"Python", ".venv", []string{"pyproject.toml", "requirements.txt", "setup.py"}},
+	{"Python", "venv", []string{"pyproject.toml", "requirements.txt", "setup.py"}},
+	{"Python", "dist", []string{"pyproject.toml", "setup.py"}},
+	{"CocoaPods", "Pods", []string{"Podfile"}},
+	{"Dart", ".dart_tool", []string{"pubspec.yaml"}},
+	{"Terraform", ".terraform", []string{"*.tf"}},
+}
+
+// vendoredDir 改动中的一个依赖目录及其统计
+type vendoredDir struct {
+	path      string
+	ecosystem string
+	files     int
+	added     int
+	deleted   int
+}
+
+// vendoredDirDetector 判断文件是否位于依赖目录中，缓存清单文件的检查结果
+type vendoredDirDetector struct {
+	root      string
+	manifests map[string]bool
+}
+
+// hasManifest 判断仓库中的 dir 目录下是否有任一清单文件
+func (d *vendoredDirDetector) hasManifest(dir string, manifests []string) bool {
+	for _, manifest := range manifests {
+		key := filepath.Join(dir, manifest)
+		found, ok := d.manifests[key]
+		if !ok {
+			matches, _ := filepath.Glob(filepath.Join(d.root, key))
+			found = len(matches) > 0
+			d.manifests[key] = found
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// match 返回文件所在的依赖目录（相对仓库根目录，以 / 结尾）和生态，不在依赖目录中时返回空
+func (d *vendoredDirDetector) match(path string) (string, string) {
+	segments := strings.Split(path, "/")
+	// 最后一段是文件名，只检查目录
+	for i := 0; i < len(segments)-1; i++ {
+		parent := strings.Join(segments[:i], "/")
+		for _, e := range ecosystemDirs {
+			dir := strings.Split(e.dir, "/")
+			if i+len(dir) >= len(segments) || strings.Join(segments[i:i+len(dir)], "/") != e.dir {
+				continue
+			}
+			if d.hasManifest(parent, e.manifests) {
+				return strings.Join(segments[:i+len(dir)], "/") + "/", e.ecosystem
+			}
+		}
+	}
+	return "", ""
+}
+
+// excludeVendoredFiles 将依赖目录中的文件合并为每个目录一条统计信息
+//
+// node_modules、vendor 等目录一次更新可能有成千上万个文件，逐个放入提示词会挤掉
+// 项目本身的改动。设置 include_vendored 时不排除。
+func excludeVendoredFiles(files []fileDiff) ([]fileDiff, []vendoredDir) {
+	if config.IncludeVendored || len(files) == 0 {
+		return files, nil
+	}
+	root, err := currentVCS.root()
+	if err != nil {
+		return files, nil
+	}
+
+	detector := &vendoredDirDetector{root: root, manifests: make(map[string]bool)}
+	var kept []fileDiff
+	var dirs []vendoredDir
+	index := make(map[string]int)
+	for _, f := range files {
+		dir, ecosystem := detector.match(f.path)
+		if dir == "" {
+			kept = append(kept, f)
+			continue
+		}
+		i, ok := index[dir]
+		if !ok {
+			i = len(dirs)
+			index[dir] = i
+			dirs = append(dirs, vendoredDir{path: dir, ecosystem: ecosystem})
+			// 占位，之后替换为统计信息，保持目录在差异中的位置
+			kept = append(kept, fileDiff{path: dir})
+		}
+		_, hunks := splitHunks(f.text)
+		added, deleted := diffStat(hunks)
+		dirs[i].files++
+		dirs[i].added += added
+		dirs[i].deleted += deleted
+	}
+	if len(dirs) == 0 {
+		return files, nil
+	}
+
+	for i, f := range kept {
+		if j, ok := index[f.path]; ok && f.text == "" {
+			d := dirs[j]
+			kept[i].text = fmt.Sprintf("%s | %d files changed, %d insertions(+), %d deletions(-), content excluded (third-party dependencies or build output, %s)", d.path, d.files, d.added, d.deleted, d.ecosystem)
+		}
+	}
+	return kept, dirs
+}
+
+// isVendoredDir 判断差异是否为合并后的依赖目录统计信息
+func isVendoredDir(f fileDiff, dirs []vendoredDir) bool {
+	for _, d := range dirs {
+		if f.path == d.path {
+			return true
+		}
+	}
+	return false
+}
+
+// vendoredDirsHint 告知模型哪些依赖目录的内容没有放入提示词
+func vendoredDirsHint(dirs []vendoredDir) string {
+	if len(dirs) == 0 {
+		return ""
+	}
+
+	described := make([]string, len(dirs))
+	for i, d := range dirs {
+		described[i] = fmt.Sprintf("%s (%s, %d files)", d.path, d.ecosystem, d.files)
+	}
+	return fmt.Sprintf("These directories hold third-party dependencies or build output, so their content was excluded: %s. Mention them only briefly (for example as updated dependencies) and focus on the project's own changes.", strings.Join(described, ", "))
+}